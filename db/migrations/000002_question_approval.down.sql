@@ -0,0 +1,2 @@
+ALTER TABLE "questions" ALTER COLUMN "approved" DROP NOT NULL;
+ALTER TABLE "questions" ALTER COLUMN "approved" DROP DEFAULT;
@@ -0,0 +1,9 @@
+-- questions used to be shown right away, so everything asked in an event without moderation counts as approved
+UPDATE "questions" SET "approved" = TRUE
+FROM "events"
+WHERE "questions"."event_id" = "events"."event_id" AND "events"."moderation" IS NOT TRUE;
+
+UPDATE "questions" SET "approved" = FALSE WHERE "approved" IS NULL;
+
+ALTER TABLE "questions" ALTER COLUMN "approved" SET DEFAULT FALSE;
+ALTER TABLE "questions" ALTER COLUMN "approved" SET NOT NULL;
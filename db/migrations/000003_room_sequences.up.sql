@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS "room_sequences"(
+    "event_id" uuid NOT NULL,
+    "seq" bigint NOT NULL DEFAULT 0,
+    CONSTRAINT "room_sequences_pkey" PRIMARY KEY ("event_id"),
+    CONSTRAINT "fk_event" FOREIGN KEY ("event_id") REFERENCES "events"("event_id") ON DELETE CASCADE
+);
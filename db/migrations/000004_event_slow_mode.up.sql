@@ -0,0 +1,2 @@
+-- seconds a participant has to wait between two questions in the event, 0 turns slow mode off
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "slow_mode" integer NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "questions_event_created_idx";
@@ -0,0 +1,3 @@
+-- the question list is sorted by time within an event
+-- the popular sort orders on "questions"."likes_count", which is kept in sync with the likes since 000006
+CREATE INDEX IF NOT EXISTS "questions_event_created_idx" ON "questions" ("event_id", "created_at" DESC, "question_id" DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE "questions" DROP CONSTRAINT IF EXISTS "valid_likes_count";
+ALTER TABLE "questions" DROP COLUMN IF EXISTS "likes_count";
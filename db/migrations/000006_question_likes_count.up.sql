@@ -0,0 +1,8 @@
+-- the like count of every question is kept on the question, it's changed together with the likes
+ALTER TABLE "questions" ADD COLUMN IF NOT EXISTS "likes_count" integer NOT NULL DEFAULT 0;
+
+UPDATE "questions" SET "likes_count" = (
+    SELECT COUNT(*) FROM "likes" WHERE "likes"."question_id" = "questions"."question_id"
+);
+
+ALTER TABLE "questions" ADD CONSTRAINT "valid_likes_count" CHECK ("likes_count" >= 0);
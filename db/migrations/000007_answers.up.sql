@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "answers"(
+    "answer_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "question_id" uuid NOT NULL,
+    "admin_id" uuid NOT NULL,
+    "content" text NOT NULL DEFAULT '',
+    "link" text NOT NULL DEFAULT '',
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "answer_pkey" PRIMARY KEY ("answer_id"),
+    CONSTRAINT "fk_question" FOREIGN KEY ("question_id") REFERENCES "questions"("question_id") ON DELETE CASCADE,
+    CONSTRAINT "fk_admin" FOREIGN KEY ("admin_id") REFERENCES "admins"("admin_id") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "answers_question_idx" ON "answers" ("question_id", "created_at");
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS "polls"(
+    "poll_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "event_id" uuid NOT NULL,
+    "question" text NOT NULL,
+    "type" varchar(20) NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'draft',
+    "rating_max" integer NOT NULL DEFAULT 0,
+    "results" jsonb,
+    "opened_at" timestamp,
+    "closed_at" timestamp,
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "poll_pkey" PRIMARY KEY ("poll_id"),
+    CONSTRAINT "fk_event" FOREIGN KEY ("event_id") REFERENCES "events"("event_id") ON DELETE CASCADE,
+    CONSTRAINT "valid_poll_type" CHECK ("type" IN ('single', 'multiple', 'rating')),
+    CONSTRAINT "valid_poll_status" CHECK ("status" IN ('draft', 'open', 'closed'))
+);
+
+CREATE INDEX IF NOT EXISTS "polls_event_idx" ON "polls" ("event_id", "created_at");
+
+CREATE TABLE IF NOT EXISTS "poll_options"(
+    "option_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "poll_id" uuid NOT NULL,
+    "content" text NOT NULL,
+    "position" integer NOT NULL,
+    CONSTRAINT "poll_option_pkey" PRIMARY KEY ("option_id"),
+    CONSTRAINT "fk_poll" FOREIGN KEY ("poll_id") REFERENCES "polls"("poll_id") ON DELETE CASCADE
+);
+
+-- a user votes once in a poll, a multiple choice vote picks several options
+CREATE TABLE IF NOT EXISTS "poll_votes"(
+    "vote_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "poll_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "rating" integer,
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "poll_vote_pkey" PRIMARY KEY ("vote_id"),
+    CONSTRAINT "fk_poll" FOREIGN KEY ("poll_id") REFERENCES "polls"("poll_id") ON DELETE CASCADE,
+    CONSTRAINT "unique_poll_vote_user" UNIQUE ("poll_id", "user_id")
+);
+
+CREATE TABLE IF NOT EXISTS "poll_vote_options"(
+    "vote_id" uuid NOT NULL,
+    "option_id" uuid NOT NULL,
+    CONSTRAINT "poll_vote_option_pkey" PRIMARY KEY ("vote_id", "option_id"),
+    CONSTRAINT "fk_vote" FOREIGN KEY ("vote_id") REFERENCES "poll_votes"("vote_id") ON DELETE CASCADE,
+    CONSTRAINT "fk_option" FOREIGN KEY ("option_id") REFERENCES "poll_options"("option_id") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "poll_vote_options_option_idx" ON "poll_vote_options" ("option_id");
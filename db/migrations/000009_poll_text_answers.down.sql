@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS "poll_votes_term_idx";
+
+ALTER TABLE "poll_votes" DROP COLUMN IF EXISTS "approved";
+
+ALTER TABLE "poll_votes" DROP COLUMN IF EXISTS "term";
+
+ALTER TABLE "poll_votes" DROP COLUMN IF EXISTS "content";
+
+DELETE FROM "polls" WHERE "type" = 'text';
+
+ALTER TABLE "polls" DROP CONSTRAINT IF EXISTS "valid_poll_type";
+
+ALTER TABLE "polls" ADD CONSTRAINT "valid_poll_type" CHECK ("type" IN ('single', 'multiple', 'rating'));
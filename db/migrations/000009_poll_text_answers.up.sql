@@ -0,0 +1,13 @@
+ALTER TABLE "polls" DROP CONSTRAINT IF EXISTS "valid_poll_type";
+
+ALTER TABLE "polls" ADD CONSTRAINT "valid_poll_type" CHECK ("type" IN ('single', 'multiple', 'rating', 'text'));
+
+-- the answer of an open text poll as it was written and as it's counted
+-- answers wait for the admin when the event is moderated
+ALTER TABLE "poll_votes" ADD COLUMN IF NOT EXISTS "content" text NOT NULL DEFAULT '';
+
+ALTER TABLE "poll_votes" ADD COLUMN IF NOT EXISTS "term" text NOT NULL DEFAULT '';
+
+ALTER TABLE "poll_votes" ADD COLUMN IF NOT EXISTS "approved" boolean NOT NULL DEFAULT true;
+
+CREATE INDEX IF NOT EXISTS "poll_votes_term_idx" ON "poll_votes" ("poll_id", "term") WHERE "term" <> '' AND "approved";
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "quiz_answers";
+
+DROP TABLE IF EXISTS "quiz_players";
+
+DROP TABLE IF EXISTS "quiz_options";
+
+DROP TABLE IF EXISTS "quiz_rounds";
+
+DROP TABLE IF EXISTS "quizzes";
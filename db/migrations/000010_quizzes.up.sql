@@ -0,0 +1,69 @@
+CREATE TABLE IF NOT EXISTS "quizzes"(
+    "quiz_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "event_id" uuid NOT NULL,
+    "title" text NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'draft',
+    "current_round" integer NOT NULL DEFAULT -1,
+    "results" jsonb,
+    "started_at" timestamp,
+    "finished_at" timestamp,
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "quiz_pkey" PRIMARY KEY ("quiz_id"),
+    CONSTRAINT "fk_event" FOREIGN KEY ("event_id") REFERENCES "events"("event_id") ON DELETE CASCADE,
+    CONSTRAINT "valid_quiz_status" CHECK ("status" IN ('draft', 'running', 'finished'))
+);
+
+CREATE INDEX IF NOT EXISTS "quizzes_event_idx" ON "quizzes" ("event_id", "created_at");
+
+-- a round is open from opened_at until closes_at, or until the admin moves on
+CREATE TABLE IF NOT EXISTS "quiz_rounds"(
+    "round_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "quiz_id" uuid NOT NULL,
+    "position" integer NOT NULL,
+    "question" text NOT NULL,
+    "time_limit" integer NOT NULL,
+    "points" integer NOT NULL,
+    "opened_at" timestamp,
+    "closes_at" timestamp,
+    "ended_at" timestamp,
+    CONSTRAINT "quiz_round_pkey" PRIMARY KEY ("round_id"),
+    CONSTRAINT "fk_quiz" FOREIGN KEY ("quiz_id") REFERENCES "quizzes"("quiz_id") ON DELETE CASCADE,
+    CONSTRAINT "unique_quiz_round_position" UNIQUE ("quiz_id", "position")
+);
+
+CREATE TABLE IF NOT EXISTS "quiz_options"(
+    "option_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "round_id" uuid NOT NULL,
+    "content" text NOT NULL,
+    "correct" boolean NOT NULL DEFAULT false,
+    "position" integer NOT NULL,
+    CONSTRAINT "quiz_option_pkey" PRIMARY KEY ("option_id"),
+    CONSTRAINT "fk_round" FOREIGN KEY ("round_id") REFERENCES "quiz_rounds"("round_id") ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "quiz_players"(
+    "quiz_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "display_name" varchar(50) NOT NULL,
+    "score" integer NOT NULL DEFAULT 0,
+    "correct_answers" integer NOT NULL DEFAULT 0,
+    "joined_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "quiz_player_pkey" PRIMARY KEY ("quiz_id", "user_id"),
+    CONSTRAINT "fk_quiz" FOREIGN KEY ("quiz_id") REFERENCES "quizzes"("quiz_id") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "quiz_players_score_idx" ON "quiz_players" ("quiz_id", "score" DESC);
+
+-- a player answers a round once
+CREATE TABLE IF NOT EXISTS "quiz_answers"(
+    "round_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "option_id" uuid NOT NULL,
+    "correct" boolean NOT NULL,
+    "points" integer NOT NULL,
+    "answered_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "quiz_answer_pkey" PRIMARY KEY ("round_id", "user_id"),
+    CONSTRAINT "fk_round" FOREIGN KEY ("round_id") REFERENCES "quiz_rounds"("round_id") ON DELETE CASCADE,
+    CONSTRAINT "fk_option" FOREIGN KEY ("option_id") REFERENCES "quiz_options"("option_id") ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "filter_hits";
+
+DROP TABLE IF EXISTS "banned_words";
+
+ALTER TABLE "events" DROP CONSTRAINT IF EXISTS "valid_filter_action";
+
+ALTER TABLE "events" DROP COLUMN IF EXISTS "filter_action";
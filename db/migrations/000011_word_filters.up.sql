@@ -0,0 +1,34 @@
+-- what happens to a question with banned words: it's rejected, the words are masked, or it waits for the admin
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "filter_action" varchar(20) NOT NULL DEFAULT 'moderate';
+
+ALTER TABLE "events" ADD CONSTRAINT "valid_filter_action" CHECK ("filter_action" IN ('reject', 'mask', 'moderate'));
+
+-- the banned words of an event, the words without an event are the global list kept by the master
+CREATE TABLE IF NOT EXISTS "banned_words"(
+    "word_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "event_id" uuid,
+    "pattern" text NOT NULL,
+    "regex" boolean NOT NULL DEFAULT false,
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "banned_word_pkey" PRIMARY KEY ("word_id"),
+    CONSTRAINT "fk_event" FOREIGN KEY ("event_id") REFERENCES "events"("event_id") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "banned_words_event_idx" ON "banned_words" ("event_id");
+
+-- every question that hit the filter, for the admin to review
+CREATE TABLE IF NOT EXISTS "filter_hits"(
+    "hit_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "event_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "question_id" uuid,
+    "content" text NOT NULL,
+    "matched" text NOT NULL,
+    "action" varchar(20) NOT NULL,
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "filter_hit_pkey" PRIMARY KEY ("hit_id"),
+    CONSTRAINT "fk_event" FOREIGN KEY ("event_id") REFERENCES "events"("event_id") ON DELETE CASCADE,
+    CONSTRAINT "fk_question" FOREIGN KEY ("question_id") REFERENCES "questions"("question_id") ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS "filter_hits_event_idx" ON "filter_hits" ("event_id", "created_at" DESC);
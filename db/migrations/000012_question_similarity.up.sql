@@ -0,0 +1,4 @@
+-- near duplicate questions are found by the trigram similarity of their content
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS "questions_content_trgm_idx" ON "questions" USING gin ("content" gin_trgm_ops);
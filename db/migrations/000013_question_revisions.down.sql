@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "question_revisions";
+
+ALTER TABLE "events" DROP COLUMN IF EXISTS "lock_edits";
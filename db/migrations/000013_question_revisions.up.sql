@@ -0,0 +1,18 @@
+-- when edits are locked a question can't be edited by its author once it has likes or is approved
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "lock_edits" boolean NOT NULL DEFAULT false;
+
+-- every edit of a question, the editor is the participant who asked it or the event admin
+CREATE TABLE IF NOT EXISTS "question_revisions"(
+    "revision_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "question_id" uuid NOT NULL,
+    "previous_content" text NOT NULL,
+    "content" text NOT NULL,
+    "editor" varchar(20) NOT NULL,
+    "editor_id" uuid NOT NULL,
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "question_revision_pkey" PRIMARY KEY ("revision_id"),
+    CONSTRAINT "fk_question" FOREIGN KEY ("question_id") REFERENCES "questions"("question_id") ON DELETE CASCADE,
+    CONSTRAINT "valid_editor" CHECK ("editor" IN ('participant', 'admin'))
+);
+
+CREATE INDEX IF NOT EXISTS "question_revisions_question_idx" ON "question_revisions" ("question_id", "created_at");
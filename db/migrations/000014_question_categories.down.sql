@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "questions_category_idx";
+
+ALTER TABLE "questions" DROP CONSTRAINT IF EXISTS "fk_category";
+
+ALTER TABLE "questions" DROP COLUMN IF EXISTS "category_id";
+
+DROP TABLE IF EXISTS "question_categories";
+
+ALTER TABLE "events" DROP COLUMN IF EXISTS "require_category";
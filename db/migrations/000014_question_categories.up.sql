@@ -0,0 +1,22 @@
+-- when a category is required a question can't be asked without one
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "require_category" boolean NOT NULL DEFAULT false;
+
+-- the categories the event admin defines for the questions of an event
+CREATE TABLE IF NOT EXISTS "question_categories"(
+    "category_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "event_id" uuid NOT NULL,
+    "name" varchar(50) NOT NULL,
+    "position" integer NOT NULL DEFAULT 0,
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "question_category_pkey" PRIMARY KEY ("category_id"),
+    CONSTRAINT "fk_event" FOREIGN KEY ("event_id") REFERENCES "events"("event_id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "question_categories_name_idx" ON "question_categories" ("event_id", lower("name"));
+
+-- a question without a category has a null category, deleting a category keeps its questions
+ALTER TABLE "questions" ADD COLUMN IF NOT EXISTS "category_id" uuid;
+
+ALTER TABLE "questions" ADD CONSTRAINT "fk_category" FOREIGN KEY ("category_id") REFERENCES "question_categories"("category_id") ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS "questions_category_idx" ON "questions" ("event_id", "category_id");
@@ -0,0 +1,11 @@
+-- the room messages too large for a notification, the notification only carries the message id
+-- the listeners read the message right away so the rows are only kept for a few minutes
+CREATE TABLE IF NOT EXISTS "room_messages"(
+    "message_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+    "event_id" uuid NOT NULL,
+    "msg" text NOT NULL,
+    "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "room_messages_pkey" PRIMARY KEY ("message_id")
+);
+
+CREATE INDEX IF NOT EXISTS "room_messages_created_idx" ON "room_messages" ("created_at");
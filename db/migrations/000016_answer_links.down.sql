@@ -0,0 +1 @@
+-- the removed links aren't kept, there is nothing to put back
@@ -0,0 +1,2 @@
+-- answer links are shown to every participant, the links that aren't http or https are removed
+UPDATE "answers" SET "link" = '' WHERE "link" <> '' AND "link" !~* '^https?://';
@@ -0,0 +1,4 @@
+-- the revisions of merged duplicates don't belong to the question they moved to
+DELETE FROM "question_revisions" WHERE "merged_from" IS NOT NULL;
+
+ALTER TABLE "question_revisions" DROP COLUMN IF EXISTS "merged_from";
@@ -0,0 +1,3 @@
+-- the revisions of a merged duplicate move to the question it was merged into
+-- merged_from keeps the id of the duplicate, so they aren't mistaken for edits of that question
+ALTER TABLE "question_revisions" ADD COLUMN IF NOT EXISTS "merged_from" uuid;
@@ -0,0 +1,17 @@
+-- the audience every instance counts in its own event rooms, refreshed every few seconds
+-- the audience of a room is the sum of the rows refreshed lately, the rows of a stopped instance go stale
+CREATE TABLE IF NOT EXISTS "room_presence"(
+    "instance_id" uuid NOT NULL,
+    "event_id" uuid NOT NULL,
+    "current" integer NOT NULL,
+    "peak" integer NOT NULL,
+    "peak_at" timestamp,
+    "joins" integer NOT NULL,
+    "leaves" integer NOT NULL,
+    "trend" jsonb NOT NULL DEFAULT '[]',
+    "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "room_presence_pkey" PRIMARY KEY ("instance_id", "event_id")
+);
+
+CREATE INDEX IF NOT EXISTS "room_presence_event_idx" ON "room_presence" ("event_id", "updated_at");
+CREATE INDEX IF NOT EXISTS "room_presence_updated_idx" ON "room_presence" ("updated_at");
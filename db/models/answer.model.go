@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Answer is a written reply of the event admin to a question
+type Answer struct {
+	AnswerID   uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	QuestionID uuid.UUID `gorm:"not null"`
+	AdminID    uuid.UUID `gorm:"not null"`
+	Content    string    `gorm:"not null"`
+	Link       string    `gorm:"not null"`
+	CreatedAt  time.Time `gorm:"not null"`
+	UpdatedAt  time.Time `gorm:"not null"`
+}
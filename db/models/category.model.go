@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QuestionCategory is a topic the event admin defines for the questions of an event, like "Product" or "HR"
+type QuestionCategory struct {
+	CategoryID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	EventID    uuid.UUID `gorm:"not null"`
+	Name       string    `gorm:"not null"`
+	Position   int       `gorm:"not null"`
+	CreatedAt  time.Time `gorm:"not null"`
+}
@@ -39,6 +39,10 @@ type Event struct {
 	Moderation        bool           `gorm:"not null"`
 	MaxQuestions      MaxQuestions   `gorm:"not null"`
 	MaxQuestionLength QuestionLength `gorm:"not null"`
+	SlowMode          int            `gorm:"not null"` // seconds between two questions of a participant, 0 is off
+	FilterAction      FilterAction   `gorm:"not null"` // what happens to a question with banned words
+	LockEdits         bool           `gorm:"not null"` // the author can't edit a question that has likes or is approved
+	RequireCategory   bool           `gorm:"not null"` // a question can't be asked without a category
 	EventCode         string         `gorm:"not null"`
 	StartDate         time.Time      `gorm:"not null"`
 	CreatedAt         time.Time      `gorm:"not null"`
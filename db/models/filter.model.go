@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FilterAction string
+
+const (
+	RejectFilter   FilterAction = "reject"
+	MaskFilter     FilterAction = "mask"
+	ModerateFilter FilterAction = "moderate"
+)
+
+type BannedWord struct {
+	WordID    uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4()"`
+	EventID   *uuid.UUID // nil for the global list of the master
+	Pattern   string     `gorm:"not null"`
+	Regex     bool       `gorm:"not null"`
+	CreatedAt time.Time  `gorm:"not null"`
+}
+
+type FilterHit struct {
+	HitID      uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	EventID    uuid.UUID `gorm:"not null"`
+	UserID     uuid.UUID `gorm:"not null"`
+	QuestionID *uuid.UUID
+	Content    string       `gorm:"not null"` // the question as the participant wrote it
+	Matched    string       `gorm:"not null"` // the banned words found in it
+	Action     FilterAction `gorm:"not null"`
+	CreatedAt  time.Time    `gorm:"not null"`
+}
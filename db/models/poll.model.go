@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PollType string
+
+const (
+	SingleChoicePoll   PollType = "single"
+	MultipleChoicePoll PollType = "multiple"
+	RatingPoll         PollType = "rating"
+	TextPoll           PollType = "text"
+)
+
+type PollStatus string
+
+const (
+	DraftPoll  PollStatus = "draft"
+	OpenPoll   PollStatus = "open"
+	ClosedPoll PollStatus = "closed"
+)
+
+type Poll struct {
+	PollID    uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4()"`
+	EventID   uuid.UUID  `gorm:"not null"`
+	Question  string     `gorm:"not null"`
+	Type      PollType   `gorm:"not null"`
+	Status    PollStatus `gorm:"not null"`
+	RatingMax int        `gorm:"not null"`
+	Results   []byte     `gorm:"type:jsonb"` // the results frozen when the poll was closed
+	OpenedAt  *time.Time
+	ClosedAt  *time.Time
+	CreatedAt time.Time    `gorm:"not null"`
+	UpdatedAt time.Time    `gorm:"not null"`
+	Event     Event        `gorm:"foreignKey:EventID;references:EventID"`
+	Options   []PollOption `gorm:"references:PollID"`
+}
+
+type PollOption struct {
+	OptionID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	PollID   uuid.UUID `gorm:"not null"`
+	Content  string    `gorm:"not null"`
+	Position int       `gorm:"not null"`
+}
+
+type PollVote struct {
+	VoteID    uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	PollID    uuid.UUID `gorm:"not null"`
+	UserID    uuid.UUID `gorm:"not null"`
+	Rating    *int
+	Content   string    `gorm:"not null"` // the answer of a text poll as it was written
+	Term      string    `gorm:"not null"` // the answer of a text poll as it's counted
+	Approved  bool      `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+type PollVoteOption struct {
+	VoteID   uuid.UUID `gorm:"not null"`
+	OptionID uuid.UUID `gorm:"not null"`
+}
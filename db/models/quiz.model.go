@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type QuizStatus string
+
+const (
+	DraftQuiz    QuizStatus = "draft"
+	RunningQuiz  QuizStatus = "running"
+	FinishedQuiz QuizStatus = "finished"
+)
+
+type Quiz struct {
+	QuizID       uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4()"`
+	EventID      uuid.UUID  `gorm:"not null"`
+	Title        string     `gorm:"not null"`
+	Status       QuizStatus `gorm:"not null"`
+	CurrentRound int        `gorm:"not null"`   // the position of the round being played, -1 before the first round
+	Results      []byte     `gorm:"type:jsonb"` // the leaderboard frozen when the quiz was finished
+	StartedAt    *time.Time
+	FinishedAt   *time.Time
+	CreatedAt    time.Time   `gorm:"not null"`
+	UpdatedAt    time.Time   `gorm:"not null"`
+	Event        Event       `gorm:"foreignKey:EventID;references:EventID"`
+	Rounds       []QuizRound `gorm:"references:QuizID"`
+}
+
+type QuizRound struct {
+	RoundID   uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	QuizID    uuid.UUID `gorm:"not null"`
+	Position  int       `gorm:"not null"`
+	Question  string    `gorm:"not null"`
+	TimeLimit int       `gorm:"not null"` // in seconds
+	Points    int       `gorm:"not null"` // for a correct answer given right away
+	OpenedAt  *time.Time
+	ClosesAt  *time.Time
+	EndedAt   *time.Time
+	Options   []QuizOption `gorm:"foreignKey:RoundID;references:RoundID"`
+}
+
+type QuizOption struct {
+	OptionID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	RoundID  uuid.UUID `gorm:"not null"`
+	Content  string    `gorm:"not null"`
+	Correct  bool      `gorm:"not null"`
+	Position int       `gorm:"not null"`
+}
+
+type QuizPlayer struct {
+	QuizID         uuid.UUID `gorm:"not null"`
+	UserID         uuid.UUID `gorm:"not null"`
+	DisplayName    string    `gorm:"not null"`
+	Score          int       `gorm:"not null"`
+	CorrectAnswers int       `gorm:"not null"`
+	JoinedAt       time.Time `gorm:"not null"`
+}
+
+type QuizAnswer struct {
+	RoundID    uuid.UUID `gorm:"not null"`
+	UserID     uuid.UUID `gorm:"not null"`
+	OptionID   uuid.UUID `gorm:"not null"`
+	Correct    bool      `gorm:"not null"`
+	Points     int       `gorm:"not null"`
+	AnsweredAt time.Time `gorm:"not null"`
+}
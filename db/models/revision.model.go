@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// who edited a question
+type Editor string
+
+const (
+	ParticipantEditor Editor = "participant"
+	AdminEditor       Editor = "admin"
+)
+
+// QuestionRevision is one edit of a question
+// the editor id is the user id of the participant or the admin id of the event admin
+// a revision of a duplicate that was merged into the question keeps the id of the duplicate in MergedFrom
+type QuestionRevision struct {
+	RevisionID      uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	QuestionID      uuid.UUID `gorm:"not null"`
+	PreviousContent string    `gorm:"not null"`
+	Content         string    `gorm:"not null"`
+	Editor          Editor    `gorm:"not null"`
+	EditorID        uuid.UUID `gorm:"not null"`
+	CreatedAt       time.Time `gorm:"not null"`
+	MergedFrom      *uuid.UUID
+}
@@ -21,6 +21,15 @@ type Config struct {
 
 	DatabaseTimeout int `mapstructure:"DATABASE_TIMEOUT"`
 
+	// memory for a single instance, postgres to run several instances
+	BroadcastBackend string `mapstructure:"BROADCAST_BACKEND"`
+
+	// websocket message budgets per type, like "createQuestion=3/10s,toggleLike=10/5s,default=20/10s"
+	WebSocketRateLimits string `mapstructure:"WS_RATE_LIMITS"`
+
+	// like counts are sent to the room in batches over this window, like "500ms", 0 sends every like right away
+	LikeBatchInterval time.Duration `mapstructure:"LIKE_BATCH_INTERVAL"`
+
 	AccessTokenPrivateKey  string        `mapstructure:"ACCESS_TOKEN_PRIVATE_KEY"`
 	AccessTokenPublicKey   string        `mapstructure:"ACCESS_TOKEN_PUBLIC_KEY"`
 	RefreshTokenPrivateKey string        `mapstructure:"REFRESH_TOKEN_PRIVATE_KEY"`
@@ -21,6 +21,9 @@ var (
 	router *gin.Engine
 )
 
+// a websocket message can carry a question of 480 characters, up to 4 bytes each, plus its envelope
+const maxWebSocketMessageSize = 4096
+
 func init() {
 	router = gin.Default()
 	err := config.LoadConfig(".env")
@@ -39,7 +42,7 @@ func main() {
 		AllowOrigins:     []string{config.GlobalConfig.ClientOrigin, "http://localhost:5173", "http://192.168.1.15:5173"},
 		AllowMethods:     []string{"POST", "OPTIONS", "GET", "PUT", "PATCH", "DELETE"},
 		AllowHeaders:     []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", " Authorization", " accept", "origin", "Cache-Control", " X-Requested-With", "ngrok-skip-browser-warning"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "X-Next-Cursor"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour}))
 
@@ -64,6 +67,8 @@ func main() {
 
 	// melody
 	m := melody.New()
+	// the default of 512 bytes can't fit a question of the longest allowed length
+	m.Config.MaxMessageSize = maxWebSocketMessageSize
 	// controller
 	controllers.InitializeControllers(m)
 	// routes
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/internal/config"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
+	"github.com/google/uuid"
+	"github.com/olahol/melody"
+	"gorm.io/gorm"
+)
+
+type AnswerController interface {
+	// websocket
+	CreateAnswer(s *melody.Session, b []byte)
+	EditAnswer(s *melody.Session, b []byte)
+	DeleteAnswer(s *melody.Session, b []byte)
+}
+
+type answerController struct {
+	DB   *gorm.DB
+	Room services.RoomService
+}
+
+func NewAnswerController(db *gorm.DB, room services.RoomService) AnswerController {
+	return &answerController{
+		DB:   db,
+		Room: room,
+	}
+}
+
+// websocket
+// CreateAnswer lets the event admin write an answer to a question of the event
+func (ac *answerController) CreateAnswer(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.CreateAnswerInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Answer, b, &payload) {
+		return
+	}
+
+	question, ok := findEventAdminQuestion(ac.DB.WithContext(dbTimeoutCtx), s, dtos.Answer, payload.QuestionID)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	newAnswer := models.Answer{
+		QuestionID: question.QuestionID,
+		AdminID:    question.Event.AdminID,
+		Content:    payload.Content,
+		Link:       payload.Link,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	answerResult := ac.DB.WithContext(dbTimeoutCtx).Create(&newAnswer)
+	if answerResult.Error != nil {
+		log.Println(answerResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Answer, dtos.InternalErrorCode, answerResult.Error.Error())
+		return
+	}
+
+	broadcastForQuestion(ac.Room, &question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Answer, dtos.CreateAnswerType, dtos.GenerateAnswerResponse(&newAnswer)))
+}
+
+// EditAnswer lets the event admin change an answer
+func (ac *answerController) EditAnswer(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.EditAnswerInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Answer, b, &payload) {
+		return
+	}
+
+	answer, question, ok := ac.findAdminAnswer(dbTimeoutCtx, s, payload.AnswerID)
+	if !ok {
+		return
+	}
+
+	answer.Content = payload.Content
+	answer.Link = payload.Link
+	answer.UpdatedAt = time.Now().UTC()
+
+	updateAnswerResult := ac.DB.WithContext(dbTimeoutCtx).Model(&models.Answer{}).Where("answer_id = ?", answer.AnswerID).Updates(map[string]any{
+		"content":    answer.Content,
+		"link":       answer.Link,
+		"updated_at": answer.UpdatedAt,
+	})
+	if updateAnswerResult.Error != nil {
+		log.Println(updateAnswerResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Answer, dtos.InternalErrorCode, updateAnswerResult.Error.Error())
+		return
+	}
+
+	broadcastForQuestion(ac.Room, &question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Answer, dtos.EditAnswerType, dtos.GenerateAnswerResponse(&answer)))
+}
+
+// DeleteAnswer lets the event admin remove an answer
+func (ac *answerController) DeleteAnswer(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.DeleteAnswerInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Answer, b, &payload) {
+		return
+	}
+
+	answer, question, ok := ac.findAdminAnswer(dbTimeoutCtx, s, payload.AnswerID)
+	if !ok {
+		return
+	}
+
+	deleteAnswerResult := ac.DB.WithContext(dbTimeoutCtx).Delete(&models.Answer{}, "answer_id = ?", answer.AnswerID)
+	if deleteAnswerResult.Error != nil {
+		log.Println(deleteAnswerResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Answer, dtos.InternalErrorCode, deleteAnswerResult.Error.Error())
+		return
+	}
+
+	broadcastForQuestion(ac.Room, &question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Answer, dtos.DeleteAnswerType, map[string]any{
+		"answer_id":   answer.AnswerID,
+		"question_id": answer.QuestionID,
+	}))
+}
+
+// findAdminAnswer finds the answer and its question and checks that the admin on the session owns the event
+// it writes the error back to the session and returns false when the admin isn't allowed
+func (ac *answerController) findAdminAnswer(ctx context.Context, s *melody.Session, answerId uuid.UUID) (models.Answer, models.Question, bool) {
+	answer := models.Answer{}
+	answerResult := ac.DB.WithContext(ctx).Where("answer_id = ?", answerId).First(&answer)
+	if answerResult.Error != nil {
+		switch answerResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Answer, dtos.AnswerNotFoundCode, "there is no answer with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Answer, dtos.InternalErrorCode, answerResult.Error.Error())
+		}
+		return answer, models.Question{}, false
+	}
+
+	question, ok := findEventAdminQuestion(ac.DB.WithContext(ctx), s, dtos.Answer, answer.QuestionID)
+	return answer, question, ok
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// how many categories an event can have
+const maxEventCategories = 20
+
+type CategoryController interface {
+	// http
+	GetEventCategories(ctx *gin.Context)
+	CreateCategory(ctx *gin.Context)
+	DeleteCategory(ctx *gin.Context)
+	GetCategoryCounts(ctx *gin.Context)
+}
+
+type categoryController struct {
+	DB   *gorm.DB
+	Room services.RoomService
+}
+
+func NewCategoryController(db *gorm.DB, room services.RoomService) CategoryController {
+	return &categoryController{
+		DB:   db,
+		Room: room,
+	}
+}
+
+// GetEventCategories lists the categories the participants can tag their questions with
+func (cc *categoryController) GetEventCategories(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	event := models.Event{}
+	eventResult := cc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", ctx.Param("event_id")).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	categories, err := cc.eventCategories(dbTimeoutCtx, event.EventID)
+	if err != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	categoriesResponse := []dtos.CategoryResponse{}
+	for _, category := range categories {
+		categoriesResponse = append(categoriesResponse, *dtos.GenerateCategoryResponse(&category))
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, categoriesResponse)
+}
+
+// CreateCategory adds a category at the end of the event's list
+func (cc *categoryController) CreateCategory(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	var payload dtos.CreateCategoryInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "name can't be empty")
+		return
+	}
+
+	// start a transaction
+	// the event row stays locked until commit so two new categories can't both take the last place
+	tx := cc.DB.Begin()
+
+	event := models.Event{}
+	eventResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("event_id = ?", ctx.Param("event_id")).First(&event)
+	if eventResult.Error != nil {
+		tx.Rollback()
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	// a deleted category leaves a gap in the positions so the next position comes after the last one
+	var existing struct {
+		TotalCategories int64
+		NextPosition    int
+	}
+	countResult := tx.WithContext(dbTimeoutCtx).Model(&models.QuestionCategory{}).Select("COUNT(*) AS total_categories, COALESCE(MAX(position) + 1, 0) AS next_position").Where("event_id = ?", event.EventID).Scan(&existing)
+	if countResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, countResult.Error.Error())
+		return
+	}
+
+	if existing.TotalCategories >= maxEventCategories {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusBadRequest, fmt.Sprintf("an event can only have %d categories", maxEventCategories))
+		return
+	}
+
+	newCategory := models.QuestionCategory{
+		EventID:   event.EventID,
+		Name:      name,
+		Position:  existing.NextPosition,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	categoryResult := tx.WithContext(dbTimeoutCtx).Create(&newCategory)
+	if categoryResult.Error != nil && strings.Contains(categoryResult.Error.Error(), "duplicate key value violates unique") {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusConflict, "there is already a category with that name")
+		return
+	} else if categoryResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, categoryResult.Error.Error())
+		return
+	}
+
+	tx.Commit()
+
+	categoryResponse := dtos.GenerateCategoryResponse(&newCategory)
+
+	// the participants can tag their questions with it right away
+	cc.Room.Broadcast(event.EventID, dtos.WebSocketRespondJson(dtos.Category, dtos.CreateCategoryType, categoryResponse))
+
+	dtos.RespondWithJson(ctx, http.StatusCreated, categoryResponse)
+}
+
+// DeleteCategory deletes a category of the event, its questions are kept without a category
+func (cc *categoryController) DeleteCategory(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	event, ok := findAdminEvent(cc.DB, ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	category := models.QuestionCategory{}
+	deleteCategoryResult := cc.DB.WithContext(dbTimeoutCtx).Clauses(clause.Returning{}).Where("category_id = ? AND event_id = ?", ctx.Param("category_id"), event.EventID).Delete(&category)
+	if deleteCategoryResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, deleteCategoryResult.Error.Error())
+		return
+	}
+
+	if deleteCategoryResult.RowsAffected < 1 {
+		dtos.RespondWithError(ctx, http.StatusNotFound, "there is no category with the given id")
+		return
+	}
+
+	// the clients drop the category from the questions that had it
+	cc.Room.Broadcast(event.EventID, dtos.WebSocketRespondJson(dtos.Category, dtos.DeleteCategoryType, map[string]any{
+		"category_id": category.CategoryID,
+	}))
+
+	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully delete category")
+}
+
+// GetCategoryCounts counts the questions of every category of the event for the event admin
+// the questions without a category are counted last
+func (cc *categoryController) GetCategoryCounts(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	event, ok := findAdminEvent(cc.DB, ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	categories, err := cc.eventCategories(dbTimeoutCtx, event.EventID)
+	if err != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	counts := []dtos.CategoryCountResponse{}
+	countsResult := cc.DB.WithContext(dbTimeoutCtx).Model(&models.Question{}).
+		Select(`category_id, COUNT(*) AS questions_count,
+			COUNT(*) FILTER (WHERE NOT approved) AS pending_count,
+			COUNT(*) FILTER (WHERE answered) AS answered_count`).
+		Where("event_id = ?", event.EventID).
+		Group("category_id").
+		Scan(&counts)
+	if countsResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, countsResult.Error.Error())
+		return
+	}
+
+	categoryCounts := map[uuid.UUID]dtos.CategoryCountResponse{}
+	uncategorized := dtos.CategoryCountResponse{}
+	for _, count := range counts {
+		if count.CategoryID == nil {
+			uncategorized = count
+			continue
+		}
+		categoryCounts[*count.CategoryID] = count
+	}
+
+	// a category without questions is counted too
+	countsResponse := []dtos.CategoryCountResponse{}
+	for _, category := range categories {
+		count := categoryCounts[category.CategoryID]
+		count.CategoryID = dtos.CheckNil(category.CategoryID)
+		count.Name = category.Name
+		countsResponse = append(countsResponse, count)
+	}
+	countsResponse = append(countsResponse, uncategorized)
+
+	dtos.RespondWithJson(ctx, http.StatusOK, countsResponse)
+}
+
+// eventCategories loads the categories of the event in the order the admin added them
+func (cc *categoryController) eventCategories(ctx context.Context, eventId uuid.UUID) ([]models.QuestionCategory, error) {
+	categories := []models.QuestionCategory{}
+	categoriesResult := cc.DB.WithContext(ctx).Where("event_id = ?", eventId).Order("position ASC, created_at ASC").Find(&categories)
+	return categories, categoriesResult.Error
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/HudYuSa/mydeen/db/models"
 	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
 	"github.com/HudYuSa/mydeen/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -31,15 +32,22 @@ type EventController interface {
 	UpdateModeration(ctx *gin.Context)
 	UpdateMaxQuestionLength(ctx *gin.Context)
 	UpdateMaxQuestions(ctx *gin.Context)
+	UpdateSlowMode(ctx *gin.Context)
+	UpdateFilterAction(ctx *gin.Context)
+	UpdateLockEdits(ctx *gin.Context)
+	UpdateRequireCategory(ctx *gin.Context)
+	GetEventPresence(ctx *gin.Context)
 }
 
 type eventController struct {
-	DB *gorm.DB
+	DB   *gorm.DB
+	Room services.RoomService
 }
 
-func NewEventController(db *gorm.DB) EventController {
+func NewEventController(db *gorm.DB, room services.RoomService) EventController {
 	return &eventController{
-		DB: db,
+		DB:   db,
+		Room: room,
 	}
 }
 
@@ -79,6 +87,7 @@ func (ec *eventController) CreateEvent(ctx *gin.Context) {
 		Moderation:        false,
 		MaxQuestions:      models.MidCount,
 		MaxQuestionLength: models.VeryLong,
+		FilterAction:      models.ModerateFilter,
 		EventCode:         randomCode,
 		StartDate:         date,
 		CreatedAt:         now,
@@ -349,6 +358,9 @@ func (ec *eventController) StartEvent(ctx *gin.Context) {
 
 	tx.Commit()
 
+	// tell everyone in the room that they can start asking
+	ec.broadcastEvent(&event, dtos.EventStatusType)
+
 	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully started your event")
 }
 
@@ -405,6 +417,9 @@ func (ec *eventController) FinishEvent(ctx *gin.Context) {
 
 	tx.Commit()
 
+	// tell everyone in the room that the event is closed
+	ec.broadcastEvent(&event, dtos.EventStatusType)
+
 	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully finished your event")
 }
 
@@ -458,6 +473,9 @@ func (ec *eventController) UpdateName(ctx *gin.Context) {
 
 	tx.Commit()
 
+	// tell everyone in the room the new name
+	ec.broadcastEvent(&event, dtos.EventNameUpdatedType)
+
 	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event name")
 }
 
@@ -515,6 +533,9 @@ func (ec *eventController) UpdateDate(ctx *gin.Context) {
 
 	tx.Commit()
 
+	// tell everyone in the room the new start date
+	ec.broadcastEvent(&event, dtos.EventDateUpdatedType)
+
 	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event date")
 }
 
@@ -560,6 +581,9 @@ func (ec *eventController) UpdateModeration(ctx *gin.Context) {
 
 	tx.Commit()
 
+	// tell everyone in the room whether new questions wait for approval
+	ec.broadcastEvent(&event, dtos.EventModerationUpdatedType)
+
 	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event moderation")
 }
 
@@ -611,6 +635,9 @@ func (ec *eventController) UpdateMaxQuestionLength(ctx *gin.Context) {
 
 	tx.Commit()
 
+	// tell everyone in the room the new length limit
+	ec.broadcastEvent(&event, dtos.EventMaxQuestionLengthUpdatedType)
+
 	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event max question length")
 }
 
@@ -662,5 +689,243 @@ func (ec *eventController) UpdateMaxQuestions(ctx *gin.Context) {
 
 	tx.Commit()
 
+	// tell everyone in the room the new question quota
+	ec.broadcastEvent(&event, dtos.EventMaxQuestionsUpdatedType)
+
 	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event max questions")
 }
+
+func (ec *eventController) UpdateSlowMode(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	eventId := ctx.Param("event_id")
+	var payload dtos.UpdateSlowModeInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx := ec.DB.Begin()
+
+	// get event by event_id
+	event := models.Event{}
+	eventResult := tx.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		tx.Rollback()
+		switch eventResult.Error.Error() {
+		case "record not found":
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	// update the wait between two questions
+	updateEventResult := tx.WithContext(dbTimeoutCtx).Model(&models.Event{}).Where("event_id = ?", event.EventID).Updates(map[string]interface{}{
+		"slow_mode":  *payload.SlowMode,
+		"updated_at": time.Now().UTC(),
+	})
+	if updateEventResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, updateEventResult.Error.Error())
+		return
+	}
+
+	tx.Commit()
+
+	// tell everyone in the room how long to wait between questions
+	event.SlowMode = *payload.SlowMode
+	ec.broadcastEvent(&event, dtos.EventSlowModeUpdatedType)
+
+	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event slow mode")
+}
+
+func (ec *eventController) UpdateFilterAction(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	eventId := ctx.Param("event_id")
+	var payload dtos.UpdateFilterActionInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// get event by event_id
+	event := models.Event{}
+	eventResult := ec.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error.Error() {
+		case "record not found":
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	// the participants aren't told, the filter only matters to the admin
+	updateEventResult := ec.DB.WithContext(dbTimeoutCtx).Model(&models.Event{}).Where("event_id = ?", event.EventID).Updates(map[string]interface{}{
+		"filter_action": payload.FilterAction,
+		"updated_at":    time.Now().UTC(),
+	})
+	if updateEventResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, updateEventResult.Error.Error())
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event filter action")
+}
+
+func (ec *eventController) UpdateLockEdits(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	eventId := ctx.Param("event_id")
+	var payload dtos.UpdateLockEditsInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// get event by event_id
+	event := models.Event{}
+	eventResult := ec.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error.Error() {
+		case "record not found":
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	updateEventResult := ec.DB.WithContext(dbTimeoutCtx).Model(&models.Event{}).Where("event_id = ?", event.EventID).Updates(map[string]interface{}{
+		"lock_edits": *payload.LockEdits,
+		"updated_at": time.Now().UTC(),
+	})
+	if updateEventResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, updateEventResult.Error.Error())
+		return
+	}
+
+	// tell everyone in the room whether the questions can still be edited
+	event.LockEdits = *payload.LockEdits
+	ec.broadcastEvent(&event, dtos.EventLockEditsUpdatedType)
+
+	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event lock edits")
+}
+
+func (ec *eventController) UpdateRequireCategory(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	eventId := ctx.Param("event_id")
+	var payload dtos.UpdateRequireCategoryInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// get event by event_id
+	event := models.Event{}
+	eventResult := ec.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error.Error() {
+		case "record not found":
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	updateEventResult := ec.DB.WithContext(dbTimeoutCtx).Model(&models.Event{}).Where("event_id = ?", event.EventID).Updates(map[string]interface{}{
+		"require_category": *payload.RequireCategory,
+		"updated_at":       time.Now().UTC(),
+	})
+	if updateEventResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, updateEventResult.Error.Error())
+		return
+	}
+
+	// tell everyone in the room whether a question needs a category
+	event.RequireCategory = *payload.RequireCategory
+	ec.broadcastEvent(&event, dtos.EventRequireCategoryUpdatedType)
+
+	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully update event require category")
+}
+
+func (ec *eventController) GetEventPresence(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	eventId := ctx.Param("event_id")
+
+	// get event by event_id
+	event := models.Event{}
+	eventResult := ec.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error.Error() {
+		case "record not found":
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, ec.Room.Presence(event.EventID))
+}
+
+// broadcastEvent tells the sessions in the event room about the change of the event
+// every message carries the whole event so the clients don't have to reload it
+func (ec *eventController) broadcastEvent(event *models.Event, t dtos.WebSocketType) {
+	eventResponse := dtos.GenerateEventResponse(event)
+	// the admin isn't loaded and participants don't need it
+	eventResponse.Admin = nil
+
+	ec.Room.Broadcast(event.EventID, dtos.WebSocketRespondJson(dtos.Event, t, eventResponse))
+}
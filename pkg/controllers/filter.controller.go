@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// how many filter hits are returned at most
+const maxFilterHitPage = 500
+
+type FilterController interface {
+	// event admin
+	GetEventWords(ctx *gin.Context)
+	AddEventWord(ctx *gin.Context)
+	DeleteEventWord(ctx *gin.Context)
+	GetEventHits(ctx *gin.Context)
+
+	// master
+	GetGlobalWords(ctx *gin.Context)
+	AddGlobalWord(ctx *gin.Context)
+	DeleteGlobalWord(ctx *gin.Context)
+}
+
+type filterController struct {
+	DB *gorm.DB
+}
+
+func NewFilterController(db *gorm.DB) FilterController {
+	return &filterController{
+		DB: db,
+	}
+}
+
+// GetEventWords lists the banned words of the event, the global list isn't in it
+func (fc *filterController) GetEventWords(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	event, ok := findAdminEvent(fc.DB, ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	words := []models.BannedWord{}
+	wordsResult := fc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", event.EventID).Order("created_at ASC").Find(&words)
+	if wordsResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, wordsResult.Error.Error())
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, generateBannedWordsResponse(words))
+}
+
+func (fc *filterController) AddEventWord(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	var payload dtos.AddBannedWordInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	event, ok := findAdminEvent(fc.DB, ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	fc.addWord(ctx, dbTimeoutCtx, &event.EventID, payload)
+}
+
+func (fc *filterController) DeleteEventWord(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	event, ok := findAdminEvent(fc.DB, ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	fc.deleteWord(ctx, fc.DB.WithContext(dbTimeoutCtx).Where("word_id = ? AND event_id = ?", ctx.Param("word_id"), event.EventID))
+}
+
+// GetEventHits lists the latest questions that hit the filter in the event, newest first
+func (fc *filterController) GetEventHits(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	event, ok := findAdminEvent(fc.DB, ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	limit := 100
+	if limitQuery := ctx.Query("limit"); limitQuery != "" {
+		var err error
+		limit, err = strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxFilterHitPage {
+			dtos.RespondWithError(ctx, http.StatusBadRequest, fmt.Sprintf("limit has to be a number between 1 and %d", maxFilterHitPage))
+			return
+		}
+	}
+
+	hits := []models.FilterHit{}
+	hitsResult := fc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", event.EventID).Order("created_at DESC").Limit(limit).Find(&hits)
+	if hitsResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, hitsResult.Error.Error())
+		return
+	}
+
+	hitsResponse := []dtos.FilterHitResponse{}
+	for _, hit := range hits {
+		hitsResponse = append(hitsResponse, *dtos.GenerateFilterHitResponse(&hit))
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, hitsResponse)
+}
+
+// GetGlobalWords lists the banned words of every event
+func (fc *filterController) GetGlobalWords(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	words := []models.BannedWord{}
+	wordsResult := fc.DB.WithContext(dbTimeoutCtx).Where("event_id IS NULL").Order("created_at ASC").Find(&words)
+	if wordsResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, wordsResult.Error.Error())
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, generateBannedWordsResponse(words))
+}
+
+func (fc *filterController) AddGlobalWord(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	var payload dtos.AddBannedWordInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fc.addWord(ctx, dbTimeoutCtx, nil, payload)
+}
+
+func (fc *filterController) DeleteGlobalWord(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	fc.deleteWord(ctx, fc.DB.WithContext(dbTimeoutCtx).Where("word_id = ? AND event_id IS NULL", ctx.Param("word_id")))
+}
+
+// addWord checks the pattern and adds it to the list of the event, or to the global list without an event
+func (fc *filterController) addWord(ctx *gin.Context, dbTimeoutCtx context.Context, eventId *uuid.UUID, payload dtos.AddBannedWordInput) {
+	pattern := strings.TrimSpace(payload.Pattern)
+
+	if !payload.Regex && services.NormalizeFilterText(pattern) == "" {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "pattern needs at least one word")
+		return
+	}
+
+	if _, err := services.CompileBannedWord(pattern, payload.Regex); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "pattern isn't a valid regular expression: "+err.Error())
+		return
+	}
+
+	newWord := models.BannedWord{
+		EventID:   eventId,
+		Pattern:   pattern,
+		Regex:     payload.Regex,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	wordResult := fc.DB.WithContext(dbTimeoutCtx).Create(&newWord)
+	if wordResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, wordResult.Error.Error())
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusCreated, dtos.GenerateBannedWordResponse(&newWord))
+}
+
+// deleteWord deletes the word the query finds
+func (fc *filterController) deleteWord(ctx *gin.Context, query *gorm.DB) {
+	deleteWordResult := query.Delete(&models.BannedWord{})
+	if deleteWordResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, deleteWordResult.Error.Error())
+		return
+	}
+
+	if deleteWordResult.RowsAffected < 1 {
+		dtos.RespondWithError(ctx, http.StatusNotFound, "there is no banned word with the given id")
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully delete banned word")
+}
+
+// findAdminEvent finds the event of the event_id param and checks that the current admin owns it
+// it responds with the error and returns false when the admin isn't allowed
+func findAdminEvent(db *gorm.DB, ctx *gin.Context, dbTimeoutCtx context.Context) (models.Event, bool) {
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	event := models.Event{}
+	eventResult := db.WithContext(dbTimeoutCtx).Where("event_id = ?", ctx.Param("event_id")).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return event, false
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return event, false
+	}
+
+	return event, true
+}
+
+func generateBannedWordsResponse(words []models.BannedWord) []dtos.BannedWordResponse {
+	wordsResponse := []dtos.BannedWordResponse{}
+	for _, word := range words {
+		wordsResponse = append(wordsResponse, *dtos.GenerateBannedWordResponse(&word))
+	}
+	return wordsResponse
+}
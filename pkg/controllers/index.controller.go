@@ -1,7 +1,11 @@
 package controllers
 
 import (
+	"log"
+
+	"github.com/HudYuSa/mydeen/internal/config"
 	"github.com/HudYuSa/mydeen/internal/connection"
+	"github.com/HudYuSa/mydeen/pkg/services"
 	"github.com/olahol/melody"
 )
 
@@ -12,15 +16,42 @@ var (
 	Event     EventController
 	Question  QuestionController
 	Like      LikeController
+	Answer    AnswerController
+	Poll      PollController
+	Quiz      QuizController
+	Filter    FilterController
+	Category  CategoryController
 	WebSocket WebSocketController
+	Stream    StreamController
 )
 
 func InitializeControllers(melody *melody.Melody) {
+	// services
+	broadcaster, err := services.NewBroadcaster(config.GlobalConfig.BroadcastBackend, connection.DB)
+	if err != nil {
+		log.Fatal("? Could not create the broadcast backend ", err)
+	}
+	room := services.NewRoomService(melody, broadcaster)
+	rateBudgets, err := services.ParseRateBudgets(config.GlobalConfig.WebSocketRateLimits)
+	if err != nil {
+		log.Fatal("? Could not read the websocket rate limits ", err)
+	}
+	rateLimiter := services.NewRateLimiter(rateBudgets)
+	likeBatcher := services.NewLikeBatcher(connection.DB, room, config.GlobalConfig.LikeBatchInterval)
+	pollBatcher := services.NewPollBatcher(connection.DB, room)
+	wordFilter := services.NewWordFilter(connection.DB)
+
 	Common = NewCommonController(connection.DB)
 	Master = NewMasterController(connection.DB)
 	Admin = NewAdminController(connection.DB)
-	Event = NewEventController(connection.DB)
-	Question = NewQuestionController(connection.DB, melody)
-	Like = NewLikeController(connection.DB, melody)
-	WebSocket = NewWebSocketController(Question, Like, melody)
+	Event = NewEventController(connection.DB, room)
+	Question = NewQuestionController(connection.DB, room, wordFilter)
+	Like = NewLikeController(connection.DB, room, likeBatcher)
+	Answer = NewAnswerController(connection.DB, room)
+	Poll = NewPollController(connection.DB, room, pollBatcher)
+	Quiz = NewQuizController(connection.DB, room)
+	Filter = NewFilterController(connection.DB)
+	Category = NewCategoryController(connection.DB, room)
+	Stream = NewStreamController(connection.DB, Question, room)
+	WebSocket = NewWebSocketController(connection.DB, Question, Like, Answer, Poll, Quiz, room, rateLimiter, melody)
 }
@@ -2,15 +2,17 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"time"
 
 	"github.com/HudYuSa/mydeen/db/models"
 	"github.com/HudYuSa/mydeen/internal/config"
 	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
+	"github.com/google/uuid"
 	"github.com/olahol/melody"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type LikeController interface {
@@ -20,14 +22,16 @@ type LikeController interface {
 }
 
 type likeController struct {
-	DB     *gorm.DB
-	Melody *melody.Melody
+	DB          *gorm.DB
+	Room        services.RoomService
+	LikeBatcher services.LikeBatcher
 }
 
-func NewLikeController(db *gorm.DB, melody *melody.Melody) LikeController {
+func NewLikeController(db *gorm.DB, room services.RoomService, likeBatcher services.LikeBatcher) LikeController {
 	return &likeController{
-		DB:     db,
-		Melody: melody,
+		DB:          db,
+		Room:        room,
+		LikeBatcher: likeBatcher,
 	}
 }
 
@@ -45,46 +49,107 @@ func (lc *likeController) ToggleLike(s *melody.Session, b []byte) {
 	// get like
 	var payload dtos.ToggleLikeInput
 
-	if err := json.Unmarshal(b, &payload); err != nil {
-		s.Write(dtos.WebSocketRespondError(dtos.Like, err.Error()))
+	if !dtos.WebSocketBindJson(s, dtos.Like, b, &payload) {
 		return
 	}
 
+	// find the question to know which event room to respond to
+	// a pending question isn't there for anyone but the admin and its author, so it can't be liked
+	question := models.Question{}
+	questionResult := lc.DB.WithContext(dbTimeoutCtx).Select("question_id", "event_id").Preload("Event").Where("question_id = ? AND approved = ?", payload.QuestionID, true).First(&question)
+	if questionResult.Error != nil {
+		switch questionResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Like, dtos.QuestionNotFoundCode, "there is no question with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Like, dtos.InternalErrorCode, questionResult.Error.Error())
+		}
+		return
+	}
+
+	// questions can only be liked while the event is live
+	if !checkEventLive(s, dtos.Like, &question.Event) {
+		return
+	}
+
+	// start a transaction
+	// the question row stays locked until commit so toggles of the same question run one after another
+	// and a double click can't race into the unique like constraint
+	tx := lc.DB.Begin()
+
+	// the question can have gone back to the moderation queue since it was found
+	lockResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.Locking{Strength: "NO KEY UPDATE"}).Select("question_id").Where("question_id = ? AND approved = ?", payload.QuestionID, true).First(&models.Question{})
+	if lockResult.Error != nil {
+		tx.Rollback()
+		switch lockResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Like, dtos.QuestionNotFoundCode, "there is no question with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Like, dtos.InternalErrorCode, lockResult.Error.Error())
+		}
+		return
+	}
+
+	// take the like back if there is one
 	like := models.Like{}
-	// check for like in database
-	checkLikeResult := lc.DB.WithContext(dbTimeoutCtx).Where("question_id = ? AND user_id = ?", payload.QuestionID, user.ID).First(&like)
-
-	if checkLikeResult.Error != nil {
-		// there's no like then create a new like in the database
-		if checkLikeResult.Error == gorm.ErrRecordNotFound {
-			like.QuestionID = payload.QuestionID
-			like.UserID = user.ID
-
-			// save new like to database
-			likeResult := lc.DB.WithContext(dbTimeoutCtx).Create(&like)
-			if likeResult.Error != nil {
-				log.Println(likeResult.Error.Error())
-				s.Write(dtos.WebSocketRespondError(dtos.Like, likeResult.Error.Error()))
-				return
-			}
-
-			// respond with new like
-			lc.Melody.Broadcast(dtos.WebSocketRespondJson(dtos.Like, dtos.ToggleLikeType, dtos.GenerateLikeResponse(&like, true)))
-			return
-		} else {
-			log.Println(checkLikeResult.Error.Error())
-			s.Write(dtos.WebSocketRespondError(dtos.Like, checkLikeResult.Error.Error()))
+	deleteLikeResult := tx.WithContext(dbTimeoutCtx).Raw("DELETE FROM likes WHERE question_id = ? AND user_id = ? RETURNING *", payload.QuestionID, user.ID).Scan(&like)
+	if deleteLikeResult.Error != nil {
+		tx.Rollback()
+		log.Println(deleteLikeResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Like, dtos.InternalErrorCode, deleteLikeResult.Error.Error())
+		return
+	}
+
+	liked := deleteLikeResult.RowsAffected == 0
+	delta := -1
+
+	// there's no like then create a new like in the database
+	if liked {
+		like = models.Like{
+			QuestionID: payload.QuestionID,
+			UserID:     user.ID,
+		}
+
+		likeResult := tx.WithContext(dbTimeoutCtx).Create(&like)
+		if likeResult.Error != nil {
+			tx.Rollback()
+			log.Println(likeResult.Error.Error())
+			dtos.WebSocketWriteError(s, dtos.Like, dtos.InternalErrorCode, likeResult.Error.Error())
 			return
 		}
+		delta = 1
+	}
+
+	// keep the like count of the question in sync
+	var likesCount int64
+	countResult := tx.WithContext(dbTimeoutCtx).Raw("UPDATE questions SET likes_count = likes_count + ? WHERE question_id = ? RETURNING likes_count", delta, payload.QuestionID).Scan(&likesCount)
+	if countResult.Error != nil {
+		tx.Rollback()
+		log.Println(countResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Like, dtos.InternalErrorCode, countResult.Error.Error())
+		return
 	}
 
-	// if there's like then delete it from the database
-	likeResult := lc.DB.WithContext(dbTimeoutCtx).Where("question_id = ? AND user_id = ?", payload.QuestionID, user.ID).Delete(&models.Like{})
-	if likeResult.Error != nil {
-		log.Println(checkLikeResult.Error.Error())
-		s.Write(dtos.WebSocketRespondError(dtos.Like, checkLikeResult.Error.Error()))
+	// commit the transaction
+	if commitResult := tx.Commit(); commitResult.Error != nil {
+		dtos.WebSocketWriteError(s, dtos.Like, dtos.InternalErrorCode, commitResult.Error.Error())
 		return
 	}
-	// respond for deleting like
-	lc.Melody.Broadcast(dtos.WebSocketRespondJson(dtos.Like, dtos.ToggleLikeType, dtos.GenerateLikeResponse(&like, false)))
+
+	// respond with the toggled like and the new count
+	lc.respondToggle(s, question.EventID, &like, liked, likesCount)
+}
+
+// respondToggle sends the toggled like with the question's new like count
+// when likes are batched only the user's own session gets it right away, the room gets the count with the next batch
+func (lc *likeController) respondToggle(s *melody.Session, eventId uuid.UUID, like *models.Like, liked bool, likesCount int64) {
+	msg := dtos.WebSocketRespondJson(dtos.Like, dtos.ToggleLikeType, dtos.GenerateLikeResponse(like, liked, likesCount))
+
+	if lc.LikeBatcher.Enabled() {
+		s.Write(msg)
+		lc.LikeBatcher.Add(eventId, like.QuestionID)
+		return
+	}
+
+	lc.Room.Broadcast(eventId, msg)
 }
@@ -0,0 +1,745 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/internal/config"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
+	"github.com/HudYuSa/mydeen/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/olahol/melody"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// the scale of a rating poll when the admin doesn't give one
+const defaultPollRatingMax = 5
+
+type PollController interface {
+	// http
+	GetEventPolls(ctx *gin.Context)
+	CreatePoll(ctx *gin.Context)
+	OpenPoll(ctx *gin.Context)
+	ClosePoll(ctx *gin.Context)
+	DeletePoll(ctx *gin.Context)
+	GetPollResults(ctx *gin.Context)
+	GetPollAnswers(ctx *gin.Context)
+
+	// websocket
+	VotePoll(s *melody.Session, b []byte)
+	ApprovePollAnswer(s *melody.Session, b []byte)
+	RejectPollAnswer(s *melody.Session, b []byte)
+}
+
+type pollController struct {
+	DB          *gorm.DB
+	Room        services.RoomService
+	PollBatcher services.PollBatcher
+}
+
+func NewPollController(db *gorm.DB, room services.RoomService, pollBatcher services.PollBatcher) PollController {
+	return &pollController{
+		DB:          db,
+		Room:        room,
+		PollBatcher: pollBatcher,
+	}
+}
+
+// http
+// GetEventPolls lists the polls of the event with their results
+// participants only see the polls that were opened, the event admin also sees the drafts
+func (pc *pollController) GetEventPolls(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	user := ctx.MustGet("user").(dtos.User)
+
+	eventId := ctx.Param("event_id")
+
+	event := models.Event{}
+	eventResult := pc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	currentAdmin, isAdmin := ctx.Get("currentAdmin")
+	isEventAdmin := isAdmin && currentAdmin.(models.Admin).AdminID == event.AdminID
+
+	query := pc.DB.WithContext(dbTimeoutCtx).Preload("Options", orderPollOptions).Where("event_id = ?", event.EventID)
+	if !isEventAdmin {
+		query = query.Where("status <> ?", models.DraftPoll)
+	}
+
+	polls := []models.Poll{}
+	pollsResult := query.Order("created_at ASC").Find(&polls)
+	if pollsResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, pollsResult.Error.Error())
+		return
+	}
+
+	// the polls the user already voted in
+	votedPollIds := []uuid.UUID{}
+	votedResult := pc.DB.WithContext(dbTimeoutCtx).Model(&models.PollVote{}).Where("user_id = ? AND poll_id IN (?)", user.ID, pc.DB.Model(&models.Poll{}).Select("poll_id").Where("event_id = ?", event.EventID)).Pluck("poll_id", &votedPollIds)
+	if votedResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, votedResult.Error.Error())
+		return
+	}
+
+	voted := map[uuid.UUID]bool{}
+	for _, pollId := range votedPollIds {
+		voted[pollId] = true
+	}
+
+	pollsResponse := []dtos.PollResponse{}
+	for _, poll := range polls {
+		pollResponse := dtos.GeneratePollResponse(&poll)
+		pollResponse.Voted = voted[poll.PollID]
+
+		if poll.Status != models.DraftPoll {
+			results, err := services.CurrentPollResults(pc.DB.WithContext(dbTimeoutCtx), &poll)
+			if err != nil {
+				dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+				return
+			}
+			pollResponse.Results = &results
+		}
+
+		pollsResponse = append(pollsResponse, *pollResponse)
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, pollsResponse)
+}
+
+// CreatePoll adds a draft poll to the event, nobody sees it until it's opened
+func (pc *pollController) CreatePoll(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	eventId := ctx.Param("event_id")
+	var payload dtos.CreatePollInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// get event by event_id
+	event := models.Event{}
+	eventResult := pc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error.Error() {
+		case "record not found":
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	now := time.Now().UTC()
+	newPoll := models.Poll{
+		EventID:   event.EventID,
+		Question:  payload.Question,
+		Type:      payload.Type,
+		Status:    models.DraftPoll,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	switch payload.Type {
+	case models.RatingPoll:
+		newPoll.RatingMax = payload.RatingMax
+		if newPoll.RatingMax == 0 {
+			newPoll.RatingMax = defaultPollRatingMax
+		}
+	case models.TextPoll:
+		// a text poll is answered freely, it has no options
+	default:
+		if len(payload.Options) < 2 {
+			dtos.RespondWithError(ctx, http.StatusBadRequest, "a choice poll needs at least 2 options")
+			return
+		}
+
+		for i, content := range payload.Options {
+			newPoll.Options = append(newPoll.Options, models.PollOption{
+				Content:  strings.TrimSpace(content),
+				Position: i,
+			})
+		}
+	}
+
+	// the options are created with the poll
+	pollResult := pc.DB.WithContext(dbTimeoutCtx).Create(&newPoll)
+	if pollResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, pollResult.Error.Error())
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusCreated, dtos.GeneratePollResponse(&newPoll))
+}
+
+// OpenPoll starts the voting and shows the poll to everyone in the event room
+func (pc *pollController) OpenPoll(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	poll, ok := pc.findAdminPoll(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	// only a draft is opened, the status in the where makes two opens race safely
+	now := time.Now().UTC()
+	openResult := pc.DB.WithContext(dbTimeoutCtx).Model(&models.Poll{}).Where("poll_id = ? AND status = ?", poll.PollID, models.DraftPoll).Updates(map[string]any{
+		"status":     models.OpenPoll,
+		"opened_at":  now,
+		"updated_at": now,
+	})
+	if openResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, openResult.Error.Error())
+		return
+	}
+
+	if openResult.RowsAffected < 1 {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "only a draft poll can be opened")
+		return
+	}
+
+	poll.Status = models.OpenPoll
+	poll.OpenedAt = &now
+	poll.UpdatedAt = now
+
+	pollResponse := dtos.GeneratePollResponse(&poll)
+	pc.Room.Broadcast(poll.EventID, dtos.WebSocketRespondJson(dtos.Poll, dtos.PollOpenedType, pollResponse))
+
+	dtos.RespondWithJson(ctx, http.StatusOK, pollResponse)
+}
+
+// ClosePoll ends the voting and stores the final results of the poll
+func (pc *pollController) ClosePoll(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	poll, ok := pc.findAdminPoll(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	// votes hold a share lock on the poll, so closing waits for the votes being cast
+	// and the results counted in this transaction are final
+	tx := pc.DB.Begin()
+
+	now := time.Now().UTC()
+	closeResult := tx.WithContext(dbTimeoutCtx).Model(&models.Poll{}).Where("poll_id = ? AND status = ?", poll.PollID, models.OpenPoll).Updates(map[string]any{
+		"status":     models.ClosedPoll,
+		"closed_at":  now,
+		"updated_at": now,
+	})
+	if closeResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, closeResult.Error.Error())
+		return
+	}
+
+	if closeResult.RowsAffected < 1 {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "only an open poll can be closed")
+		return
+	}
+
+	results, err := services.PollResults(tx.WithContext(dbTimeoutCtx), &poll)
+	if err != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	storeResult := tx.WithContext(dbTimeoutCtx).Model(&models.Poll{}).Where("poll_id = ?", poll.PollID).Update("results", string(dtos.EncodeJson(results)))
+	if storeResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, storeResult.Error.Error())
+		return
+	}
+
+	tx.Commit()
+
+	poll.Status = models.ClosedPoll
+	poll.ClosedAt = &now
+	poll.UpdatedAt = now
+
+	pollResponse := dtos.GeneratePollResponse(&poll)
+	pollResponse.Results = &results
+	pc.Room.Broadcast(poll.EventID, dtos.WebSocketRespondJson(dtos.Poll, dtos.PollClosedType, pollResponse))
+
+	dtos.RespondWithJson(ctx, http.StatusOK, pollResponse)
+}
+
+// DeletePoll removes the poll with its votes
+func (pc *pollController) DeletePoll(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	poll, ok := pc.findAdminPoll(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	deletePollResult := pc.DB.WithContext(dbTimeoutCtx).Delete(&models.Poll{}, "poll_id = ?", poll.PollID)
+	if deletePollResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, deletePollResult.Error.Error())
+		return
+	}
+
+	// participants never saw a draft
+	if poll.Status != models.DraftPoll {
+		pc.Room.Broadcast(poll.EventID, dtos.WebSocketRespondJson(dtos.Poll, dtos.PollDeletedType, map[string]any{
+			"poll_id":  poll.PollID,
+			"event_id": poll.EventID,
+		}))
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully delete poll")
+}
+
+// GetPollResults exports the results of the poll
+// a closed poll gives the results stored when it was closed, an open poll its current results
+func (pc *pollController) GetPollResults(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	poll, ok := pc.findAdminPoll(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	results, err := services.CurrentPollResults(pc.DB.WithContext(dbTimeoutCtx), &poll)
+	if err != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	pollResponse := dtos.GeneratePollResponse(&poll)
+	pollResponse.Results = &results
+
+	dtos.RespondWithJson(ctx, http.StatusOK, pollResponse)
+}
+
+// GetPollAnswers lists the answers of a text poll for the event admin
+// ?status=pending gives the moderation queue, ?status=approved the published answers
+func (pc *pollController) GetPollAnswers(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	poll, ok := pc.findAdminPoll(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	if poll.Type != models.TextPoll {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "only a text poll has answers")
+		return
+	}
+
+	query := pc.DB.WithContext(dbTimeoutCtx).Where("poll_id = ?", poll.PollID)
+	switch ctx.Query("status") {
+	case "":
+	case "pending":
+		query = query.Where("approved = ?", false)
+	case "approved":
+		query = query.Where("approved = ?", true)
+	default:
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "status has to be pending or approved")
+		return
+	}
+
+	votes := []models.PollVote{}
+	votesResult := query.Order("created_at ASC").Find(&votes)
+	if votesResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, votesResult.Error.Error())
+		return
+	}
+
+	answersResponse := []dtos.PollAnswerResponse{}
+	for _, vote := range votes {
+		answersResponse = append(answersResponse, *dtos.GeneratePollAnswerResponse(&vote))
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, answersResponse)
+}
+
+// websocket
+// VotePoll casts the vote of the user in an open poll, every user votes once
+func (pc *pollController) VotePoll(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	user := sessionUser(s)
+
+	var payload dtos.VotePollInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Poll, b, &payload) {
+		return
+	}
+
+	poll := models.Poll{}
+	pollResult := pc.DB.WithContext(dbTimeoutCtx).Preload("Event").Preload("Options", orderPollOptions).Where("poll_id = ?", payload.PollID).First(&poll)
+	if pollResult.Error != nil {
+		switch pollResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.PollNotFoundCode, "there is no poll with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, pollResult.Error.Error())
+		}
+		return
+	}
+
+	// participants never see a draft
+	if poll.Status == models.DraftPoll && !isEventAdminSession(s, poll.Event.AdminID) {
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.PollNotFoundCode, "there is no poll with the given id")
+		return
+	}
+
+	// polls can only be voted while the event is live
+	if !checkEventLive(s, dtos.Poll, &poll.Event) {
+		return
+	}
+
+	if !checkPollVote(s, &poll, &payload) {
+		return
+	}
+
+	// the answers of a text poll wait for the admin when the event is moderated
+	term := utils.NormalizeTerm(payload.Text)
+	approved := poll.Type != models.TextPoll || !poll.Event.Moderation
+
+	// start a transaction
+	// the share lock keeps the poll open until the vote is in, closing waits for it
+	tx := pc.DB.Begin()
+
+	lockResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.Locking{Strength: "SHARE"}).Select("poll_id", "status").Where("poll_id = ?", poll.PollID).First(&poll)
+	if lockResult.Error != nil {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, lockResult.Error.Error())
+		return
+	}
+
+	if poll.Status != models.OpenPoll {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.PollNotOpenCode, "this poll isn't open for votes")
+		return
+	}
+
+	// the unique vote of the user in the poll decides who was first
+	vote := models.PollVote{
+		PollID:    poll.PollID,
+		UserID:    user.ID,
+		Rating:    payload.Rating,
+		Content:   strings.TrimSpace(payload.Text),
+		Term:      term,
+		Approved:  approved,
+		CreatedAt: time.Now().UTC(),
+	}
+	voteResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.OnConflict{DoNothing: true}).Create(&vote)
+	if voteResult.Error != nil {
+		tx.Rollback()
+		log.Println(voteResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, voteResult.Error.Error())
+		return
+	}
+
+	if voteResult.RowsAffected < 1 {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.AlreadyVotedCode, "you already voted in this poll")
+		return
+	}
+
+	if len(payload.OptionIDs) > 0 {
+		voteOptions := make([]models.PollVoteOption, 0, len(payload.OptionIDs))
+		for _, optionId := range payload.OptionIDs {
+			voteOptions = append(voteOptions, models.PollVoteOption{
+				VoteID:   vote.VoteID,
+				OptionID: optionId,
+			})
+		}
+
+		voteOptionsResult := tx.WithContext(dbTimeoutCtx).Create(&voteOptions)
+		if voteOptionsResult.Error != nil {
+			tx.Rollback()
+			log.Println(voteOptionsResult.Error.Error())
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, voteOptionsResult.Error.Error())
+			return
+		}
+	}
+
+	// commit the transaction
+	if commitResult := tx.Commit(); commitResult.Error != nil {
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, commitResult.Error.Error())
+		return
+	}
+
+	// the voter gets the vote right away, the room gets the results with the next batch
+	s.Write(dtos.WebSocketRespondJson(dtos.Poll, dtos.VotePollType, map[string]any{
+		"poll_id":    poll.PollID,
+		"vote_id":    vote.VoteID,
+		"option_ids": payload.OptionIDs,
+		"rating":     payload.Rating,
+		"text":       vote.Content,
+		"approved":   vote.Approved,
+	}))
+
+	if !vote.Approved {
+		// only the event admin sees the answer until it's approved
+		pc.Room.BroadcastTo(poll.EventID, dtos.WebSocketRespondJson(dtos.Poll, dtos.PendingPollAnswerType, dtos.GeneratePollAnswerResponse(&vote)), services.Audience{
+			AdminID: &poll.Event.AdminID,
+		})
+		return
+	}
+
+	pc.PollBatcher.Add(poll.EventID, poll.PollID)
+}
+
+// ApprovePollAnswer counts a pending answer of a text poll in the results
+// the results of a closed poll are final, so its pending answers can only be rejected
+func (pc *pollController) ApprovePollAnswer(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.ModeratePollAnswerInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Poll, b, &payload) {
+		return
+	}
+
+	vote, poll, ok := pc.findAdminPollAnswer(dbTimeoutCtx, s, payload.VoteID)
+	if !ok {
+		return
+	}
+
+	// the share lock keeps the poll open until the answer is approved, closing waits for it
+	tx := pc.DB.Begin()
+
+	lockResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.Locking{Strength: "SHARE"}).Select("poll_id", "status").Where("poll_id = ?", poll.PollID).First(&poll)
+	if lockResult.Error != nil {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, lockResult.Error.Error())
+		return
+	}
+
+	if poll.Status != models.OpenPoll {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.PollNotOpenCode, "the results of a closed poll can't change anymore")
+		return
+	}
+
+	// the approved in the where makes two approvals race safely
+	approveResult := tx.WithContext(dbTimeoutCtx).Model(&models.PollVote{}).Where("vote_id = ? AND approved = ?", vote.VoteID, false).Update("approved", true)
+	if approveResult.Error != nil {
+		tx.Rollback()
+		log.Println(approveResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, approveResult.Error.Error())
+		return
+	}
+
+	if approveResult.RowsAffected < 1 {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidStateCode, "this answer is already approved")
+		return
+	}
+
+	// commit the transaction
+	if commitResult := tx.Commit(); commitResult.Error != nil {
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, commitResult.Error.Error())
+		return
+	}
+
+	vote.Approved = true
+
+	// the author and the admin are told, the room gets the new counts with the next batch
+	pc.Room.BroadcastTo(poll.EventID, dtos.WebSocketRespondJson(dtos.Poll, dtos.ApprovePollAnswerType, dtos.GeneratePollAnswerResponse(&vote)), services.Audience{
+		AdminID: &poll.Event.AdminID,
+		UserID:  &vote.UserID,
+	})
+	pc.PollBatcher.Add(poll.EventID, poll.PollID)
+}
+
+// RejectPollAnswer removes a pending answer of a text poll, only the author and the event admin are told
+func (pc *pollController) RejectPollAnswer(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.ModeratePollAnswerInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Poll, b, &payload) {
+		return
+	}
+
+	vote, poll, ok := pc.findAdminPollAnswer(dbTimeoutCtx, s, payload.VoteID)
+	if !ok {
+		return
+	}
+
+	deleteVoteResult := pc.DB.WithContext(dbTimeoutCtx).Where("vote_id = ? AND approved = ?", vote.VoteID, false).Delete(&models.PollVote{})
+	if deleteVoteResult.Error != nil {
+		log.Println(deleteVoteResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, deleteVoteResult.Error.Error())
+		return
+	}
+
+	if deleteVoteResult.RowsAffected < 1 {
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidStateCode, "only pending answers can be rejected")
+		return
+	}
+
+	pc.Room.BroadcastTo(poll.EventID, dtos.WebSocketRespondJson(dtos.Poll, dtos.RejectPollAnswerType, map[string]any{
+		"vote_id": vote.VoteID,
+		"poll_id": vote.PollID,
+	}), services.Audience{
+		AdminID: &poll.Event.AdminID,
+		UserID:  &vote.UserID,
+	})
+}
+
+// checkPollVote checks that the vote fits the type of the poll
+// it writes the error back to the session and returns false when it doesn't
+func checkPollVote(s *melody.Session, poll *models.Poll, payload *dtos.VotePollInput) bool {
+	if poll.Type == models.TextPoll {
+		if payload.Rating != nil || len(payload.OptionIDs) > 0 {
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "a text poll is voted with a text only")
+			return false
+		}
+		if utils.NormalizeTerm(payload.Text) == "" {
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "the answer needs at least one word")
+			return false
+		}
+		return true
+	}
+
+	if payload.Text != "" {
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "only a text poll is voted with a text")
+		return false
+	}
+
+	switch poll.Type {
+	case models.RatingPoll:
+		if payload.Rating == nil || len(payload.OptionIDs) > 0 {
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "a rating poll is voted with a rating only")
+			return false
+		}
+		if *payload.Rating > poll.RatingMax {
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "the rating is out of the scale of the poll")
+			return false
+		}
+		return true
+
+	case models.SingleChoicePoll:
+		if len(payload.OptionIDs) != 1 {
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "a single choice poll is voted with exactly one option")
+			return false
+		}
+
+	default:
+		if len(payload.OptionIDs) < 1 {
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "a multiple choice poll is voted with at least one option")
+			return false
+		}
+	}
+
+	if payload.Rating != nil {
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "a choice poll is voted with options only")
+		return false
+	}
+
+	options := map[uuid.UUID]bool{}
+	for _, option := range poll.Options {
+		options[option.OptionID] = true
+	}
+
+	picked := map[uuid.UUID]bool{}
+	for _, optionId := range payload.OptionIDs {
+		if !options[optionId] || picked[optionId] {
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InvalidPayloadCode, "the options have to be different options of the poll")
+			return false
+		}
+		picked[optionId] = true
+	}
+
+	return true
+}
+
+// findAdminPollAnswer finds the answer and its poll and checks that the admin on the session owns the event
+// it writes the error back to the session and returns false when the admin isn't allowed
+func (pc *pollController) findAdminPollAnswer(ctx context.Context, s *melody.Session, voteId uuid.UUID) (models.PollVote, models.Poll, bool) {
+	vote := models.PollVote{}
+	voteResult := pc.DB.WithContext(ctx).Where("vote_id = ?", voteId).First(&vote)
+	if voteResult.Error != nil {
+		switch voteResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.PollAnswerNotFoundCode, "there is no answer with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, voteResult.Error.Error())
+		}
+		return vote, models.Poll{}, false
+	}
+
+	poll := models.Poll{}
+	pollResult := pc.DB.WithContext(ctx).Preload("Event").Where("poll_id = ?", vote.PollID).First(&poll)
+	if pollResult.Error != nil {
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.InternalErrorCode, pollResult.Error.Error())
+		return vote, poll, false
+	}
+
+	if !isEventAdminSession(s, poll.Event.AdminID) {
+		dtos.WebSocketWriteError(s, dtos.Poll, dtos.ForbiddenCode, "You're not allowed to access this endpoint")
+		return vote, poll, false
+	}
+
+	return vote, poll, true
+}
+
+// findAdminPoll finds the poll of the poll_id param and checks that the current admin owns its event
+// it responds with the error and returns false when the admin isn't allowed
+func (pc *pollController) findAdminPoll(ctx *gin.Context, dbTimeoutCtx context.Context) (models.Poll, bool) {
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	poll := models.Poll{}
+	pollResult := pc.DB.WithContext(dbTimeoutCtx).Preload("Event").Preload("Options", orderPollOptions).Where("poll_id = ?", ctx.Param("poll_id")).First(&poll)
+	if pollResult.Error != nil {
+		switch pollResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no poll with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, pollResult.Error.Error())
+		}
+		return poll, false
+	}
+
+	// check if admin is the admin that created the event
+	if poll.Event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return poll, false
+	}
+
+	return poll, true
+}
+
+// orderPollOptions loads the options of a poll in the order the admin gave them
+func orderPollOptions(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
@@ -2,40 +2,57 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/HudYuSa/mydeen/db/models"
 	"github.com/HudYuSa/mydeen/internal/config"
 	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/olahol/melody"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type QuestionController interface {
 	// http
 	GetEventQuestions(ctx *gin.Context)
 	GetUserTotalQuestions(ctx *gin.Context)
+	GetSimilarQuestions(ctx *gin.Context)
+	GetQuestionRevisions(ctx *gin.Context)
 	// websocket
 	CreateQuestion(s *melody.Session, b []byte)
 	DeleteQuestion(s *melody.Session, b []byte)
 	EditQuestion(s *melody.Session, b []byte)
+	ApproveQuestion(s *melody.Session, b []byte)
+	RejectQuestion(s *melody.Session, b []byte)
+	AdminDeleteQuestion(s *melody.Session, b []byte)
+	AdminEditQuestion(s *melody.Session, b []byte)
+	StarQuestion(s *melody.Session, b []byte)
+	AnswerQuestion(s *melody.Session, b []byte)
+	MergeQuestions(s *melody.Session, b []byte)
+	QuestionSnapshot(s *melody.Session, event *models.Event)
+	PublicSnapshot(ctx context.Context, event *models.Event) ([]byte, uint64, error)
 }
 
 type questionController struct {
-	DB     *gorm.DB
-	Melody *melody.Melody
+	DB         *gorm.DB
+	Room       services.RoomService
+	WordFilter services.WordFilter
 }
 
-func NewQuestionController(db *gorm.DB, melody *melody.Melody) QuestionController {
+func NewQuestionController(db *gorm.DB, room services.RoomService, wordFilter services.WordFilter) QuestionController {
 	return &questionController{
-		DB:     db,
-		Melody: melody,
+		DB:         db,
+		Room:       room,
+		WordFilter: wordFilter,
 	}
 }
 
@@ -47,31 +64,266 @@ func (qc *questionController) GetEventQuestions(ctx *gin.Context) {
 
 	eventId := ctx.Param("event_id")
 
-	questions := []models.Question{}
-	questionsResult := qc.DB.WithContext(dbTimeoutCtx).Preload("Likes").Where("event_id", eventId).Find(&questions)
-	if questionsResult.Error != nil {
-		switch questionsResult.Error.Error() {
-		case "record not found":
-			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given code")
+	event := models.Event{}
+	eventResult := qc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
 		default:
-			dtos.RespondWithError(ctx, http.StatusInternalServerError, questionsResult.Error.Error())
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
 		}
 		return
 	}
 
-	questionsResponse := []dtos.QuestionResponse{}
-	for _, question := range questions {
-		questionsResponse = append(questionsResponse, *dtos.GenerateQuestionResponse(&question, user))
+	currentAdmin, isAdmin := ctx.Get("currentAdmin")
+	isEventAdmin := isAdmin && currentAdmin.(models.Admin).AdminID == event.AdminID
+
+	list := questionList{
+		EventID:      event.EventID,
+		IsEventAdmin: isEventAdmin,
+		User:         user,
+		Sort:         dtos.QuestionSort(ctx.DefaultQuery("sort", string(dtos.RecentSort))),
+		Filter:       dtos.QuestionFilter(ctx.Query("filter")),
+	}
+
+	// the questions of one category, or the questions without one with "none"
+	if categoryQuery := ctx.Query("category"); categoryQuery == "none" {
+		list.Uncategorized = true
+	} else if categoryQuery != "" {
+		categoryId, err := uuid.Parse(categoryQuery)
+		if err != nil {
+			dtos.RespondWithError(ctx, http.StatusBadRequest, "category has to be a category id or none")
+			return
+		}
+		list.CategoryID = &categoryId
+	}
+
+	switch list.Sort {
+	case dtos.RecentSort, dtos.PopularSort, dtos.AnsweredSort:
+	default:
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "sort has to be recent, popular or answered")
+		return
+	}
+
+	// the old way for admins to ask for the moderation queue
+	if list.Filter == dtos.AllFilter && isEventAdmin && ctx.Query("status") == "pending" {
+		list.Filter = dtos.PendingFilter
+	}
+
+	switch list.Filter {
+	case dtos.AllFilter, dtos.StarredFilter, dtos.AnsweredFilter:
+	case dtos.PendingFilter:
+		// only the event admin sees the moderation queue
+		if !isEventAdmin {
+			dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+			return
+		}
+	default:
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "filter has to be starred, answered or pending")
+		return
+	}
+
+	// without a limit every question is returned, like before pagination
+	limit := 0
+	if limitQuery := ctx.Query("limit"); limitQuery != "" {
+		var err error
+		limit, err = strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxQuestionPage {
+			dtos.RespondWithError(ctx, http.StatusBadRequest, fmt.Sprintf("limit has to be a number between 1 and %d", maxQuestionPage))
+			return
+		}
+		// one more question tells if there's a next page
+		list.Limit = limit + 1
+	}
+
+	if cursorQuery := ctx.Query("cursor"); cursorQuery != "" {
+		cursor, err := dtos.DecodeQuestionCursor(cursorQuery)
+		if err != nil || cursor.Sort != list.Sort {
+			dtos.RespondWithError(ctx, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		list.Cursor = &cursor
+	}
+
+	questions, err := qc.listQuestions(dbTimeoutCtx, list)
+	if err != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// the cursor of the next page is sent in a header so the body stays a plain list
+	if limit > 0 && len(questions) > limit {
+		questions = questions[:limit]
+		last := questions[len(questions)-1]
+		ctx.Header("X-Next-Cursor", dtos.QuestionCursor{
+			Sort:       list.Sort,
+			LikesCount: last.LikesCount,
+			Answered:   last.Answered,
+			CreatedAt:  last.CreatedAt,
+			QuestionID: last.QuestionID,
+		}.Encode())
+	}
+
+	questionsResponse, err := qc.questionResponses(dbTimeoutCtx, questions)
+	if err != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	dtos.RespondWithJson(ctx, http.StatusOK, questionsResponse)
 }
 
 func (qc *questionController) GetUserTotalQuestions(ctx *gin.Context) {
-	// get user identifier
-	// get event id
-	// get questions data/total using the event id and user id
-	// return total questions
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	user := ctx.MustGet("user").(dtos.User)
+
+	eventId := ctx.Param("event_id")
+
+	event := models.Event{}
+	eventResult := qc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// count the questions the user asked in this event
+	var totalQuestions int64
+	countResult := qc.DB.WithContext(dbTimeoutCtx).Model(&models.Question{}).Where("event_id = ? AND user_id = ?", event.EventID, user.ID).Count(&totalQuestions)
+	if countResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, countResult.Error.Error())
+		return
+	}
+
+	remainingQuestions := int64(event.MaxQuestions) - totalQuestions
+	if remainingQuestions < 0 {
+		remainingQuestions = 0
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, dtos.QuestionQuotaResponse{
+		EventID:            dtos.CheckNil(event.EventID),
+		MaxQuestions:       event.MaxQuestions,
+		MaxQuestionLength:  event.MaxQuestionLength,
+		TotalQuestions:     totalQuestions,
+		RemainingQuestions: remainingQuestions,
+	})
+}
+
+// GetSimilarQuestions finds the questions of the event that look like the given content
+// the event admin can give a question_id instead to find the duplicates of a question
+func (qc *questionController) GetSimilarQuestions(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	user := ctx.MustGet("user").(dtos.User)
+
+	eventId := ctx.Param("event_id")
+
+	event := models.Event{}
+	eventResult := qc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	currentAdmin, isAdmin := ctx.Get("currentAdmin")
+	isEventAdmin := isAdmin && currentAdmin.(models.Admin).AdminID == event.AdminID
+
+	content := ctx.Query("content")
+
+	var exclude *uuid.UUID
+	if questionQuery := ctx.Query("question_id"); questionQuery != "" {
+		questionId, err := uuid.Parse(questionQuery)
+		if err != nil {
+			dtos.RespondWithError(ctx, http.StatusBadRequest, "question_id has to be a uuid")
+			return
+		}
+
+		question := models.Question{}
+		questionResult := qc.DB.WithContext(dbTimeoutCtx).Scopes(visibleQuestions(isEventAdmin, user)).Where("question_id = ? AND event_id = ?", questionId, event.EventID).First(&question)
+		if questionResult.Error != nil {
+			switch questionResult.Error {
+			case gorm.ErrRecordNotFound:
+				dtos.RespondWithError(ctx, http.StatusNotFound, "there is no question with the given id")
+			default:
+				dtos.RespondWithError(ctx, http.StatusInternalServerError, questionResult.Error.Error())
+			}
+			return
+		}
+
+		content = question.Content
+		exclude = &question.QuestionID
+	}
+
+	if strings.TrimSpace(content) == "" {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "content or question_id is required")
+		return
+	}
+
+	similar, err := similarQuestions(qc.DB.WithContext(dbTimeoutCtx), event.EventID, content, exclude, isEventAdmin)
+	if err != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, similar)
+}
+
+// GetQuestionRevisions lists every edit of a question for the event admin, the oldest first
+// the edits of the duplicates merged into the question are listed apart, grouped by the duplicate
+func (qc *questionController) GetQuestionRevisions(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	question := models.Question{}
+	questionResult := qc.DB.WithContext(dbTimeoutCtx).Preload("Event").Where("question_id = ? AND event_id = ?", ctx.Param("question_id"), ctx.Param("event_id")).First(&question)
+	if questionResult.Error != nil {
+		switch questionResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no question with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, questionResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if question.Event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	revisions := []models.QuestionRevision{}
+	revisionsResult := qc.DB.WithContext(dbTimeoutCtx).Where("question_id = ?", question.QuestionID).Order("merged_from ASC NULLS FIRST, created_at ASC").Find(&revisions)
+	if revisionsResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, revisionsResult.Error.Error())
+		return
+	}
+
+	revisionsResponse := dtos.QuestionRevisionsResponse{
+		QuestionID:      dtos.CheckNil(question.QuestionID),
+		Revisions:       []dtos.QuestionRevisionResponse{},
+		MergedRevisions: []dtos.QuestionRevisionResponse{},
+	}
+	for _, revision := range revisions {
+		if revision.MergedFrom != nil {
+			revisionsResponse.MergedRevisions = append(revisionsResponse.MergedRevisions, *dtos.GenerateQuestionRevisionResponse(&revision))
+		} else {
+			revisionsResponse.Revisions = append(revisionsResponse.Revisions, *dtos.GenerateQuestionRevisionResponse(&revision))
+		}
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, revisionsResponse)
 }
 
 // websocket
@@ -85,8 +337,7 @@ func (qc *questionController) CreateQuestion(s *melody.Session, b []byte) {
 
 	var payload dtos.CreateQuestionInput
 
-	if err := json.Unmarshal(b, &payload); err != nil {
-		s.Write(dtos.WebSocketRespondError(dtos.Question, err.Error()))
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
 		return
 	}
 
@@ -96,37 +347,176 @@ func (qc *questionController) CreateQuestion(s *melody.Session, b []byte) {
 
 	eventId, err := uuid.Parse(payload.EventID)
 	if err != nil {
-		s.Write(dtos.WebSocketRespondError(dtos.Question, "no event with the given id"))
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.EventNotFoundCode, "no event with the given id")
+		return
+	}
+
+	// start a transaction
+	// the lock of the user in this event is held until commit so two questions from the same user can't both pass the quota check
+	// it's a lock of the user only, the other participants and the admin settings don't wait on it
+	tx := qc.DB.Begin()
+
+	lockResult := tx.WithContext(dbTimeoutCtx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", eventId.String()+user.ID.String())
+	if lockResult.Error != nil {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, lockResult.Error.Error())
+		return
+	}
+
+	// find the event to know its limits and whether the question needs approval
+	event := models.Event{}
+	eventResult := tx.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		tx.Rollback()
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.EventNotFoundCode, "no event with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// questions can only be asked while the event is live
+	if !checkEventLive(s, dtos.Question, &event) {
+		tx.Rollback()
+		return
+	}
+
+	// the length is counted in unicode characters, not bytes
+	if utf8.RuneCountInString(payload.Content) > int(event.MaxQuestionLength) {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionTooLongCode, fmt.Sprintf("your question can't be longer than %d characters", event.MaxQuestionLength))
+		return
+	}
+
+	// check how many questions the user already asked in this event
+	var totalQuestions int64
+	countResult := tx.WithContext(dbTimeoutCtx).Model(&models.Question{}).Where("event_id = ? AND user_id = ?", event.EventID, user.ID).Count(&totalQuestions)
+	if countResult.Error != nil {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, countResult.Error.Error())
+		return
+	}
+
+	if totalQuestions >= int64(event.MaxQuestions) {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionLimitReachedCode, fmt.Sprintf("you can only ask %d questions in this event", event.MaxQuestions))
+		return
+	}
+
+	// in slow mode the user has to wait between two questions
+	if event.SlowMode > 0 {
+		var lastQuestion models.Question
+		lastQuestionResult := tx.WithContext(dbTimeoutCtx).Select("created_at").Where("event_id = ? AND user_id = ?", event.EventID, user.ID).Order("created_at DESC").Limit(1).Find(&lastQuestion)
+		if lastQuestionResult.Error != nil {
+			tx.Rollback()
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, lastQuestionResult.Error.Error())
+			return
+		}
+
+		cooldown := time.Duration(event.SlowMode) * time.Second
+		if lastQuestionResult.RowsAffected > 0 && now.Sub(lastQuestion.CreatedAt) < cooldown {
+			tx.Rollback()
+			wait := cooldown - now.Sub(lastQuestion.CreatedAt)
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.SlowModeCode, fmt.Sprintf("slow mode is on, you can ask again in %d seconds", int(wait.Seconds())+1))
+			return
+		}
+	}
+
+	// the category has to be one of the event's, a question needs one when the event requires it
+	if payload.CategoryID != nil {
+		categoryResult := tx.WithContext(dbTimeoutCtx).Where("category_id = ? AND event_id = ?", *payload.CategoryID, event.EventID).First(&models.QuestionCategory{})
+		if categoryResult.Error != nil {
+			tx.Rollback()
+			switch categoryResult.Error {
+			case gorm.ErrRecordNotFound:
+				dtos.WebSocketWriteError(s, dtos.Question, dtos.CategoryNotFoundCode, "there is no category with the given id in this event")
+			default:
+				dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, categoryResult.Error.Error())
+			}
+			return
+		}
+	} else if event.RequireCategory {
+		// an event without categories has nothing to pick from so it can't require one
+		var totalCategories int64
+		categoriesResult := tx.WithContext(dbTimeoutCtx).Model(&models.QuestionCategory{}).Where("event_id = ?", event.EventID).Count(&totalCategories)
+		if categoriesResult.Error != nil {
+			tx.Rollback()
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, categoriesResult.Error.Error())
+			return
+		}
+
+		if totalCategories > 0 {
+			tx.Rollback()
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.CategoryRequiredCode, "your question needs a category in this event")
+			return
+		}
+	}
+
+	// the banned words of the event and the global list decide what happens to the question
+	filtered, err := qc.WordFilter.Check(tx.WithContext(dbTimeoutCtx), event.EventID, payload.Content)
+	if err != nil {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, err.Error())
+		return
 	}
 
+	if filtered.Hit() && event.FilterAction == models.RejectFilter {
+		tx.Rollback()
+		qc.WordFilter.Log(dbTimeoutCtx, &event, user.ID, nil, payload.Content, filtered)
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.ContentBlockedCode, "your question has words that aren't allowed in this event")
+		return
+	}
+
+	content, approved := filterQuestion(&event, payload.Content, filtered)
+
 	newQuestion := models.Question{
-		EventID:   eventId,
-		UserID:    user.ID,
-		Username:  payload.Username,
-		Content:   payload.Content,
-		Starred:   false,
-		Approved:  false,
-		Answered:  false,
-		CreatedAt: now,
-		UpdatedAt: now,
+		EventID:    eventId,
+		UserID:     user.ID,
+		Username:   payload.Username,
+		Content:    content,
+		Starred:    false,
+		Approved:   approved,
+		Answered:   false,
+		CategoryID: payload.CategoryID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	}
 
 	log.Println("question instance: ", newQuestion)
 	// save new question to the database
-	questionResult := qc.DB.WithContext(dbTimeoutCtx).Create(&newQuestion)
+	questionResult := tx.WithContext(dbTimeoutCtx).Create(&newQuestion)
 	if questionResult.Error != nil && strings.Contains(questionResult.Error.Error(), "duplicate key value violates unique") {
+		tx.Rollback()
 		log.Println(questionResult.Error.Error())
-		s.Write(dtos.WebSocketRespondError(dtos.Question, questionResult.Error.Error()))
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, questionResult.Error.Error())
 		return
 	} else if questionResult.Error != nil {
+		tx.Rollback()
 		log.Println(questionResult.Error.Error())
-		s.Write(dtos.WebSocketRespondError(dtos.Question, questionResult.Error.Error()))
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, questionResult.Error.Error())
 		return
 	}
 
+	// commit the transaction
+	tx.Commit()
+
+	if filtered.Hit() {
+		qc.WordFilter.Log(dbTimeoutCtx, &event, user.ID, &newQuestion.QuestionID, payload.Content, filtered)
+	}
+
 	// respond back to the client websocket
 	log.Println(newQuestion)
-	qc.Melody.Broadcast(dtos.WebSocketRespondJson(dtos.Question, dtos.CreateQuestionType, dtos.GenerateQuestionResponse(&newQuestion, user)))
+	if !newQuestion.Approved {
+		// hold the question in the moderation queue
+		qc.broadcastQuestion(&newQuestion, event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.PendingQuestionType, dtos.GenerateQuestionResponse(&newQuestion, user)))
+	} else {
+		qc.broadcastQuestion(&newQuestion, event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.CreateQuestionType, dtos.GenerateQuestionResponse(&newQuestion, user)))
+	}
+
+	// show the author the questions that were already asked like theirs, so they can like those instead
+	qc.suggestSimilarQuestions(dbTimeoutCtx, s, &newQuestion)
 }
 
 func (qc *questionController) DeleteQuestion(s *melody.Session, b []byte) {
@@ -143,8 +533,7 @@ func (qc *questionController) DeleteQuestion(s *melody.Session, b []byte) {
 
 	var payload dtos.DeleteQuestionInput
 
-	if err := json.Unmarshal(b, &payload); err != nil {
-		s.Write(dtos.WebSocketRespondError(dtos.Question, err.Error()))
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
 		return
 	}
 
@@ -155,14 +544,14 @@ func (qc *questionController) DeleteQuestion(s *melody.Session, b []byte) {
 
 	// find the question
 	question := models.Question{}
-	questionResult := tx.WithContext(dbTimeoutCtx).Where("question_id = ?", payload.QuestionID).First(&question)
+	questionResult := tx.WithContext(dbTimeoutCtx).Preload("Event").Where("question_id = ?", payload.QuestionID).First(&question)
 	if questionResult.Error != nil {
 		tx.Rollback()
 		switch questionResult.Error.Error() {
 		case "record not found":
-			s.Write(dtos.WebSocketRespondError(dtos.Question, "there is no question with the given id"))
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionNotFoundCode, "there is no question with the given id")
 		default:
-			s.Write(dtos.WebSocketRespondError(dtos.Question, questionResult.Error.Error()))
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, questionResult.Error.Error())
 		}
 		return
 	}
@@ -170,14 +559,19 @@ func (qc *questionController) DeleteQuestion(s *melody.Session, b []byte) {
 	// check if user is the admin that created the question
 	if question.UserID != user.ID {
 		tx.Rollback()
-		s.Write(dtos.WebSocketRespondError(dtos.Question, "You're not allowed to access this endpoint"))
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.ForbiddenCode, "You're not allowed to access this endpoint")
+		return
+	}
+
+	if !checkEventLive(s, dtos.Question, &question.Event) {
+		tx.Rollback()
 		return
 	}
 
 	deleteQuestionResult := tx.WithContext(dbTimeoutCtx).Delete(&models.Question{}, "question_id = ?", question.QuestionID)
 	if deleteQuestionResult.Error != nil {
 		log.Println(deleteQuestionResult.Error.Error())
-		s.Write(dtos.WebSocketRespondError(dtos.Question, deleteQuestionResult.Error.Error()))
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, deleteQuestionResult.Error.Error())
 		return
 	}
 
@@ -185,7 +579,7 @@ func (qc *questionController) DeleteQuestion(s *melody.Session, b []byte) {
 	tx.Commit()
 
 	// kirim question id nya biar nanti di frontend semua active connection bisa delete question itu dari storenya
-	qc.Melody.Broadcast(dtos.WebSocketRespondJson(dtos.Question, dtos.DeleteQuestionType, map[string]any{
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.DeleteQuestionType, map[string]any{
 		"question_id": payload.QuestionID,
 	}))
 }
@@ -201,8 +595,7 @@ func (qc *questionController) EditQuestion(s *melody.Session, b []byte) {
 
 	var payload dtos.EditQuestionInput
 
-	if err := json.Unmarshal(b, &payload); err != nil {
-		s.Write(dtos.WebSocketRespondError(dtos.Question, err.Error()))
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
 		return
 	}
 
@@ -210,18 +603,19 @@ func (qc *questionController) EditQuestion(s *melody.Session, b []byte) {
 	log.Println("payload: ", payload)
 
 	// start a transaction
+	// the question row stays locked until commit so no like or approval slips in between the lock check and the edit
 	tx := qc.DB.Begin()
 
 	// find the question
 	question := models.Question{}
-	questionResult := tx.WithContext(dbTimeoutCtx).Where("question_id = ?", payload.QuestionID).First(&question)
+	questionResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.Locking{Strength: "NO KEY UPDATE"}).Preload("Event").Where("question_id = ?", payload.QuestionID).First(&question)
 	if questionResult.Error != nil {
 		tx.Rollback()
 		switch questionResult.Error.Error() {
 		case "record not found":
-			s.Write(dtos.WebSocketRespondError(dtos.Question, "there is no question with the given id"))
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionNotFoundCode, "there is no question with the given id")
 		default:
-			s.Write(dtos.WebSocketRespondError(dtos.Question, questionResult.Error.Error()))
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, questionResult.Error.Error())
 		}
 		return
 	}
@@ -229,22 +623,857 @@ func (qc *questionController) EditQuestion(s *melody.Session, b []byte) {
 	// check if user is the admin that created the question
 	if question.UserID != user.ID {
 		tx.Rollback()
-		s.Write(dtos.WebSocketRespondError(dtos.Question, "You're not allowed to access this endpoint"))
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.ForbiddenCode, "You're not allowed to access this endpoint")
+		return
+	}
+
+	if !checkEventLive(s, dtos.Question, &question.Event) {
+		tx.Rollback()
+		return
+	}
+
+	// with edits locked a question keeps what people liked or the admin approved
+	if question.Event.LockEdits && (question.LikesCount > 0 || question.Approved) {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionLockedCode, "this question can't be edited anymore")
+		return
+	}
+
+	// the edited question has to fit the event limit too
+	if utf8.RuneCountInString(payload.Content) > int(question.Event.MaxQuestionLength) {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionTooLongCode, fmt.Sprintf("your question can't be longer than %d characters", question.Event.MaxQuestionLength))
+		return
+	}
+
+	// an edit goes through the banned words like a new question
+	filtered, err := qc.WordFilter.Check(tx.WithContext(dbTimeoutCtx), question.EventID, payload.Content)
+	if err != nil {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, err.Error())
+		return
+	}
+
+	if filtered.Hit() && question.Event.FilterAction == models.RejectFilter {
+		tx.Rollback()
+		qc.WordFilter.Log(dbTimeoutCtx, &question.Event, user.ID, &question.QuestionID, payload.Content, filtered)
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.ContentBlockedCode, "your question has words that aren't allowed in this event")
 		return
 	}
 
+	// an approved question only goes back to the admin when the filter sends it to moderation
+	content, _ := filterQuestion(&question.Event, payload.Content, filtered)
+	heldBack := question.Approved && filtered.Hit() && question.Event.FilterAction == models.ModerateFilter
+
 	// update question data
-	question.Content = payload.Content
+	previousContent := question.Content
+	question.Content = content
+	question.Approved = question.Approved && !heldBack
+	question.UpdatedAt = time.Now().UTC()
 
-	UpdateQuestionResult := tx.WithContext(dbTimeoutCtx).Where("question_id = ?", payload.QuestionID).Save(&question)
+	UpdateQuestionResult := tx.WithContext(dbTimeoutCtx).Model(&models.Question{}).Where("question_id = ?", payload.QuestionID).Updates(map[string]any{
+		"content":    question.Content,
+		"approved":   question.Approved,
+		"updated_at": question.UpdatedAt,
+	})
 	if UpdateQuestionResult.Error != nil {
+		tx.Rollback()
 		log.Println(UpdateQuestionResult.Error.Error())
-		s.Write(dtos.WebSocketRespondError(dtos.Question, UpdateQuestionResult.Error.Error()))
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, UpdateQuestionResult.Error.Error())
+		return
+	}
+
+	if err := saveRevision(tx.WithContext(dbTimeoutCtx), &question, previousContent, models.ParticipantEditor, user.ID); err != nil {
+		tx.Rollback()
+		log.Println(err.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, err.Error())
+		return
+	}
+
+	// commit the transaction
+	tx.Commit()
+
+	if filtered.Hit() {
+		qc.WordFilter.Log(dbTimeoutCtx, &question.Event, user.ID, &question.QuestionID, payload.Content, filtered)
+	}
+
+	if heldBack {
+		// the participants drop the question until the admin approves it again
+		qc.Room.Broadcast(question.EventID, dtos.WebSocketRespondJson(dtos.Question, dtos.DeleteQuestionType, map[string]any{
+			"question_id": question.QuestionID,
+		}))
+		qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.PendingQuestionType, dtos.GenerateQuestionResponseWithLikes(&question, int64(question.LikesCount), false)))
 		return
 	}
 
-	qc.Melody.Broadcast(dtos.WebSocketRespondJson(dtos.Question, dtos.EditQuestionType, map[string]string{
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.EditQuestionType, map[string]string{
 		"question_id": payload.QuestionID,
-		"content":     payload.Content,
+		"content":     question.Content,
+	}))
+}
+
+// ApproveQuestion publishes a pending question to every participant of the event
+func (qc *questionController) ApproveQuestion(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.ModerateQuestionInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
+		return
+	}
+
+	question, ok := qc.findAdminQuestion(dbTimeoutCtx, s, payload.QuestionID)
+	if !ok {
+		return
+	}
+
+	if question.Approved {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "this question is already approved")
+		return
+	}
+
+	// only a question that is still pending is approved, so two admins approving at once broadcast it once
+	// the returned row has the likes and content of the question at the time it's approved
+	approved := models.Question{}
+	updateQuestionResult := qc.DB.WithContext(dbTimeoutCtx).Model(&approved).Clauses(clause.Returning{}).Where("question_id = ? AND approved = false", question.QuestionID).Updates(map[string]any{
+		"approved":   true,
+		"updated_at": time.Now().UTC(),
+	})
+	if updateQuestionResult.Error != nil {
+		log.Println(updateQuestionResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, updateQuestionResult.Error.Error())
+		return
+	}
+
+	if updateQuestionResult.RowsAffected < 1 {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "this question isn't pending anymore")
+		return
+	}
+
+	question.Approved = approved.Approved
+	question.Content = approved.Content
+	question.LikesCount = approved.LikesCount
+	question.UpdatedAt = approved.UpdatedAt
+
+	// participants haven't seen the question yet so they get the whole question
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.ApproveQuestionType, dtos.GenerateQuestionResponseWithLikes(&question, int64(question.LikesCount), false)))
+}
+
+// RejectQuestion removes a pending question, only the author and the event admin are told
+func (qc *questionController) RejectQuestion(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.ModerateQuestionInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
+		return
+	}
+
+	question, ok := qc.findAdminQuestion(dbTimeoutCtx, s, payload.QuestionID)
+	if !ok {
+		return
+	}
+
+	if question.Approved {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "only pending questions can be rejected")
+		return
+	}
+
+	// only a question that is still pending is rejected, an approve that got there first wins
+	deleteQuestionResult := qc.DB.WithContext(dbTimeoutCtx).Delete(&models.Question{}, "question_id = ? AND approved = false", question.QuestionID)
+	if deleteQuestionResult.Error != nil {
+		log.Println(deleteQuestionResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, deleteQuestionResult.Error.Error())
+		return
+	}
+
+	if deleteQuestionResult.RowsAffected < 1 {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "this question isn't pending anymore")
+		return
+	}
+
+	// the question is still pending here so this only reaches the author and the admin
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.RejectQuestionType, map[string]any{
+		"question_id": question.QuestionID,
 	}))
 }
+
+// AdminDeleteQuestion lets the event admin delete any question of the event
+func (qc *questionController) AdminDeleteQuestion(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.ModerateQuestionInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
+		return
+	}
+
+	question, ok := qc.findAdminQuestion(dbTimeoutCtx, s, payload.QuestionID)
+	if !ok {
+		return
+	}
+
+	// the returned row tells if the question was approved when it was deleted, so the right sessions drop it
+	deleted := models.Question{}
+	deleteQuestionResult := qc.DB.WithContext(dbTimeoutCtx).Clauses(clause.Returning{}).Where("question_id = ?", question.QuestionID).Delete(&deleted)
+	if deleteQuestionResult.Error != nil {
+		log.Println(deleteQuestionResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, deleteQuestionResult.Error.Error())
+		return
+	}
+
+	if deleteQuestionResult.RowsAffected < 1 {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionNotFoundCode, "there is no question with the given id")
+		return
+	}
+
+	question.Approved = deleted.Approved
+
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.AdminDeleteQuestionType, map[string]any{
+		"question_id": question.QuestionID,
+	}))
+}
+
+// AdminEditQuestion lets the event admin edit the content of any question of the event
+// the edit has to fit the event limit, but it skips the word filter since the admin is the one moderating the questions
+func (qc *questionController) AdminEditQuestion(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.AdminEditQuestionInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
+		return
+	}
+
+	question, ok := qc.findAdminQuestion(dbTimeoutCtx, s, payload.QuestionID)
+	if !ok {
+		return
+	}
+
+	// the length is counted in unicode characters, not bytes
+	if utf8.RuneCountInString(payload.Content) > int(question.Event.MaxQuestionLength) {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionTooLongCode, fmt.Sprintf("the question can't be longer than %d characters", question.Event.MaxQuestionLength))
+		return
+	}
+
+	// start a transaction
+	// the content is read again under the lock so the revision has what the question said right before this edit
+	tx := qc.DB.Begin()
+
+	current := models.Question{}
+	currentResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.Locking{Strength: "NO KEY UPDATE"}).Select("content").Where("question_id = ?", question.QuestionID).First(&current)
+	if currentResult.Error != nil {
+		tx.Rollback()
+		switch currentResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionNotFoundCode, "there is no question with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, currentResult.Error.Error())
+		}
+		return
+	}
+
+	question.Content = payload.Content
+	question.UpdatedAt = time.Now().UTC()
+
+	updateQuestionResult := tx.WithContext(dbTimeoutCtx).Model(&models.Question{}).Where("question_id = ?", question.QuestionID).Updates(map[string]any{
+		"content":    question.Content,
+		"updated_at": question.UpdatedAt,
+	})
+	if updateQuestionResult.Error != nil {
+		tx.Rollback()
+		log.Println(updateQuestionResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, updateQuestionResult.Error.Error())
+		return
+	}
+
+	if err := saveRevision(tx.WithContext(dbTimeoutCtx), &question, current.Content, models.AdminEditor, question.Event.AdminID); err != nil {
+		tx.Rollback()
+		log.Println(err.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, err.Error())
+		return
+	}
+
+	// commit the transaction
+	if commitResult := tx.Commit(); commitResult.Error != nil {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, commitResult.Error.Error())
+		return
+	}
+
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.AdminEditQuestionType, map[string]any{
+		"question_id": question.QuestionID,
+		"content":     question.Content,
+	}))
+}
+
+// StarQuestion lets the event admin star or unstar a question
+func (qc *questionController) StarQuestion(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.StarQuestionInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
+		return
+	}
+
+	question, ok := qc.findAdminQuestion(dbTimeoutCtx, s, payload.QuestionID)
+	if !ok {
+		return
+	}
+
+	// only a change is broadcast, the returned row tells if the question is approved by now
+	updated := models.Question{}
+	updateQuestionResult := qc.DB.WithContext(dbTimeoutCtx).Model(&updated).Clauses(clause.Returning{}).Where("question_id = ? AND starred <> ?", question.QuestionID, payload.Starred).Updates(map[string]any{
+		"starred":    payload.Starred,
+		"updated_at": time.Now().UTC(),
+	})
+	if updateQuestionResult.Error != nil {
+		log.Println(updateQuestionResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, updateQuestionResult.Error.Error())
+		return
+	}
+
+	if updateQuestionResult.RowsAffected < 1 {
+		if payload.Starred {
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "this question is already starred")
+		} else {
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "this question isn't starred")
+		}
+		return
+	}
+
+	question.Starred = updated.Starred
+	question.Approved = updated.Approved
+	question.UpdatedAt = updated.UpdatedAt
+
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.StarQuestionType, map[string]any{
+		"question_id": question.QuestionID,
+		"starred":     question.Starred,
+	}))
+}
+
+// AnswerQuestion lets the event admin mark a question as answered or not answered
+func (qc *questionController) AnswerQuestion(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.AnswerQuestionInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
+		return
+	}
+
+	question, ok := qc.findAdminQuestion(dbTimeoutCtx, s, payload.QuestionID)
+	if !ok {
+		return
+	}
+
+	// only a change is broadcast, the returned row tells if the question is approved by now
+	updated := models.Question{}
+	updateQuestionResult := qc.DB.WithContext(dbTimeoutCtx).Model(&updated).Clauses(clause.Returning{}).Where("question_id = ? AND answered <> ?", question.QuestionID, payload.Answered).Updates(map[string]any{
+		"answered":   payload.Answered,
+		"updated_at": time.Now().UTC(),
+	})
+	if updateQuestionResult.Error != nil {
+		log.Println(updateQuestionResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, updateQuestionResult.Error.Error())
+		return
+	}
+
+	if updateQuestionResult.RowsAffected < 1 {
+		if payload.Answered {
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "this question is already answered")
+		} else {
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "this question isn't answered")
+		}
+		return
+	}
+
+	question.Answered = updated.Answered
+	question.Approved = updated.Approved
+	question.UpdatedAt = updated.UpdatedAt
+
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.AnswerQuestionType, map[string]any{
+		"question_id": question.QuestionID,
+		"answered":    question.Answered,
+	}))
+}
+
+// MergeQuestions merges the duplicates into an approved question of the same event
+// the likes of the duplicates move to the question, a user who liked more than one of them is counted once
+// the answers of the duplicates move too, then the duplicates are deleted
+// the revisions of the duplicates move as well, marked with the duplicate they came from
+// so the admin can still see how the merged questions were edited without mixing them into the question's own edits
+// a pending duplicate was never shown to the room, so only the admin and its author hear that it was merged
+func (qc *questionController) MergeQuestions(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.MergeQuestionsInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Question, b, &payload) {
+		return
+	}
+
+	question, ok := findEventAdminQuestion(qc.DB.WithContext(dbTimeoutCtx), s, dtos.Question, payload.QuestionID)
+	if !ok {
+		return
+	}
+
+	if !question.Approved {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidStateCode, "duplicates can only be merged into an approved question")
+		return
+	}
+
+	duplicateIds := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, duplicateId := range payload.DuplicateIDs {
+		if duplicateId == question.QuestionID {
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidPayloadCode, "a question can't be merged into itself")
+			return
+		}
+		if !seen[duplicateId] {
+			seen[duplicateId] = true
+			duplicateIds = append(duplicateIds, duplicateId)
+		}
+	}
+
+	// start a transaction
+	// every question of the merge stays locked until commit so no like lands on a duplicate that's being deleted
+	// the rows are locked in the same order every time so two merges can't deadlock
+	tx := qc.DB.Begin()
+
+	questions := []models.Question{}
+	lockResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.Locking{Strength: "UPDATE"}).Select("question_id", "event_id", "user_id", "approved").Where("question_id IN ?", append(duplicateIds, question.QuestionID)).Order("question_id").Find(&questions)
+	if lockResult.Error != nil {
+		tx.Rollback()
+		log.Println(lockResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, lockResult.Error.Error())
+		return
+	}
+
+	if len(questions) != len(duplicateIds)+1 {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.QuestionNotFoundCode, "there is no question with some of the given ids")
+		return
+	}
+
+	approvedIds := []uuid.UUID{}
+	pendingQuestions := []models.Question{}
+	for _, q := range questions {
+		if q.EventID != question.EventID {
+			tx.Rollback()
+			dtos.WebSocketWriteError(s, dtos.Question, dtos.InvalidPayloadCode, "only questions of the same event can be merged")
+			return
+		}
+
+		if q.QuestionID == question.QuestionID {
+			continue
+		}
+		if q.Approved {
+			approvedIds = append(approvedIds, q.QuestionID)
+		} else {
+			pendingQuestions = append(pendingQuestions, q)
+		}
+	}
+
+	// a like of a user who already liked the question isn't moved
+	likesResult := tx.WithContext(dbTimeoutCtx).Exec("INSERT INTO likes (question_id, user_id) SELECT DISTINCT ?::uuid, user_id FROM likes WHERE question_id IN ? ON CONFLICT ON CONSTRAINT unique_like_user_question DO NOTHING", question.QuestionID, duplicateIds)
+	if likesResult.Error != nil {
+		tx.Rollback()
+		log.Println(likesResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, likesResult.Error.Error())
+		return
+	}
+
+	answersResult := tx.WithContext(dbTimeoutCtx).Model(&models.Answer{}).Where("question_id IN ?", duplicateIds).Update("question_id", question.QuestionID)
+	if answersResult.Error != nil {
+		tx.Rollback()
+		log.Println(answersResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, answersResult.Error.Error())
+		return
+	}
+
+	// a duplicate that had questions merged into it already keeps their merged_from
+	revisionsResult := tx.WithContext(dbTimeoutCtx).Model(&models.QuestionRevision{}).Where("question_id IN ?", duplicateIds).Updates(map[string]any{
+		"question_id": question.QuestionID,
+		"merged_from": gorm.Expr("COALESCE(merged_from, question_id)"),
+	})
+	if revisionsResult.Error != nil {
+		tx.Rollback()
+		log.Println(revisionsResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, revisionsResult.Error.Error())
+		return
+	}
+
+	// the likes left on the duplicates are deleted with them
+	deleteQuestionsResult := tx.WithContext(dbTimeoutCtx).Delete(&models.Question{}, "question_id IN ?", duplicateIds)
+	if deleteQuestionsResult.Error != nil {
+		tx.Rollback()
+		log.Println(deleteQuestionsResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, deleteQuestionsResult.Error.Error())
+		return
+	}
+
+	// keep the like count of the question in sync
+	var likesCount int64
+	countResult := tx.WithContext(dbTimeoutCtx).Raw("UPDATE questions SET likes_count = (SELECT COUNT(*) FROM likes WHERE question_id = ?), updated_at = ? WHERE question_id = ? RETURNING likes_count", question.QuestionID, time.Now().UTC(), question.QuestionID).Scan(&likesCount)
+	if countResult.Error != nil {
+		tx.Rollback()
+		log.Println(countResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, countResult.Error.Error())
+		return
+	}
+
+	// commit the transaction
+	if commitResult := tx.Commit(); commitResult.Error != nil {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, commitResult.Error.Error())
+		return
+	}
+
+	// the question is approved so everyone gets it, the clients drop the merged questions
+	qc.broadcastQuestion(&question, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.MergeQuestionsType, map[string]any{
+		"question_id": question.QuestionID,
+		"merged_ids":  approvedIds,
+		"likes_count": likesCount,
+	}))
+
+	// the pending duplicates only go to the admin and their author
+	for _, pendingQuestion := range pendingQuestions {
+		qc.broadcastQuestion(&pendingQuestion, question.Event.AdminID, dtos.WebSocketRespondJson(dtos.Question, dtos.MergeQuestionsType, map[string]any{
+			"question_id": question.QuestionID,
+			"merged_ids":  []uuid.UUID{pendingQuestion.QuestionID},
+			"likes_count": likesCount,
+		}))
+	}
+}
+
+// QuestionSnapshot sends the whole question list to a client that missed too many messages to replay
+func (qc *questionController) QuestionSnapshot(s *melody.Session, event *models.Event) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	snapshot, _, err := qc.questionSnapshot(dbTimeoutCtx, event, isEventAdminSession(s, event.AdminID), sessionUser(s))
+	if err != nil {
+		dtos.WebSocketWriteError(s, dtos.Question, dtos.InternalErrorCode, err.Error())
+		return
+	}
+
+	s.Write(snapshot)
+}
+
+// PublicSnapshot is the snapshot of the questions everyone can see, for the read-only feeds
+func (qc *questionController) PublicSnapshot(ctx context.Context, event *models.Event) ([]byte, uint64, error) {
+	return qc.questionSnapshot(ctx, event, false, dtos.User{})
+}
+
+// questionSnapshot loads the questions the user can see, stamped with the room sequence they're current to
+func (qc *questionController) questionSnapshot(ctx context.Context, event *models.Event, isEventAdmin bool, user dtos.User) ([]byte, uint64, error) {
+	// the client is already in the room so anything sent after this sequence reaches it live
+	seq := qc.Room.Seq(event.EventID)
+
+	questions, err := qc.listQuestions(ctx, questionList{
+		EventID:      event.EventID,
+		IsEventAdmin: isEventAdmin,
+		User:         user,
+		Sort:         dtos.RecentSort,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	questionsResponse, err := qc.questionResponses(ctx, questions)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return dtos.SetWebSocketSeq(dtos.WebSocketRespondJson(dtos.Question, dtos.SnapshotType, questionsResponse), seq), seq, nil
+}
+
+// the largest page of the question list
+const maxQuestionPage = 100
+
+// questionList describes which questions of an event to load and in which order
+type questionList struct {
+	EventID       uuid.UUID
+	IsEventAdmin  bool
+	User          dtos.User
+	Sort          dtos.QuestionSort
+	Filter        dtos.QuestionFilter
+	CategoryID    *uuid.UUID
+	Uncategorized bool // only the questions without a category
+	Cursor        *dtos.QuestionCursor
+	Limit         int
+}
+
+// questionRow is a question with its likes counted by the database
+type questionRow struct {
+	QuestionID uuid.UUID
+	EventID    uuid.UUID
+	UserID     uuid.UUID
+	Username   string
+	Content    string
+	Starred    bool
+	Approved   bool
+	Answered   bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	LikesCount int64
+	UserLiked  bool
+	CategoryID *uuid.UUID
+}
+
+func (row *questionRow) response() *dtos.QuestionResponse {
+	return dtos.GenerateQuestionResponseWithLikes(&models.Question{
+		QuestionID: row.QuestionID,
+		EventID:    row.EventID,
+		UserID:     row.UserID,
+		Username:   row.Username,
+		Content:    row.Content,
+		Starred:    row.Starred,
+		Approved:   row.Approved,
+		Answered:   row.Answered,
+		CategoryID: row.CategoryID,
+		CreatedAt:  row.CreatedAt,
+		UpdatedAt:  row.UpdatedAt,
+	}, row.LikesCount, row.UserLiked)
+}
+
+// listQuestions loads the questions with their like count and whether the user liked them
+// the like count is kept on the question so the likes never have to be loaded
+func (qc *questionController) listQuestions(ctx context.Context, list questionList) ([]questionRow, error) {
+	questions := qc.DB.Table("questions").
+		Select(`questions.question_id, questions.event_id, questions.user_id, COALESCE(questions.username, '') AS username, questions.content,
+			COALESCE(questions.starred, FALSE) AS starred, questions.approved, COALESCE(questions.answered, FALSE) AS answered,
+			questions.created_at, questions.updated_at,
+			questions.likes_count, questions.category_id,
+			EXISTS (SELECT 1 FROM likes WHERE likes.question_id = questions.question_id AND likes.user_id = ?) AS user_liked`, list.User.ID).
+		Where("questions.event_id = ?", list.EventID).
+		Scopes(visibleQuestions(list.IsEventAdmin, list.User))
+
+	switch list.Filter {
+	case dtos.StarredFilter:
+		questions = questions.Where("questions.starred = ?", true)
+	case dtos.AnsweredFilter:
+		questions = questions.Where("questions.answered = ?", true)
+	case dtos.PendingFilter:
+		questions = questions.Where("questions.approved = ?", false)
+	}
+
+	if list.CategoryID != nil {
+		questions = questions.Where("questions.category_id = ?", *list.CategoryID)
+	} else if list.Uncategorized {
+		questions = questions.Where("questions.category_id IS NULL")
+	}
+
+	// the questions are sorted and cut outside so the cursor can compare the selected columns
+	query := qc.DB.WithContext(ctx).Table("(?) AS q", questions)
+
+	switch list.Sort {
+	case dtos.PopularSort:
+		if list.Cursor != nil {
+			query = query.Where("(q.likes_count, q.created_at, q.question_id) < (?, ?, ?)", list.Cursor.LikesCount, list.Cursor.CreatedAt, list.Cursor.QuestionID)
+		}
+		query = query.Order("q.likes_count DESC, q.created_at DESC, q.question_id DESC")
+	case dtos.AnsweredSort:
+		if list.Cursor != nil {
+			query = query.Where("(q.answered, q.created_at, q.question_id) < (?, ?, ?)", list.Cursor.Answered, list.Cursor.CreatedAt, list.Cursor.QuestionID)
+		}
+		query = query.Order("q.answered DESC, q.created_at DESC, q.question_id DESC")
+	default:
+		if list.Cursor != nil {
+			query = query.Where("(q.created_at, q.question_id) < (?, ?)", list.Cursor.CreatedAt, list.Cursor.QuestionID)
+		}
+		query = query.Order("q.created_at DESC, q.question_id DESC")
+	}
+
+	if list.Limit > 0 {
+		query = query.Limit(list.Limit)
+	}
+
+	rows := []questionRow{}
+	rowsResult := query.Scan(&rows)
+	return rows, rowsResult.Error
+}
+
+// questionResponses turns the questions into responses with their answers
+// the answers of every question are loaded in one query
+func (qc *questionController) questionResponses(ctx context.Context, questions []questionRow) ([]dtos.QuestionResponse, error) {
+	questionsResponse := []dtos.QuestionResponse{}
+	if len(questions) == 0 {
+		return questionsResponse, nil
+	}
+
+	questionIds := make([]uuid.UUID, 0, len(questions))
+	for _, question := range questions {
+		questionIds = append(questionIds, question.QuestionID)
+	}
+
+	answers := []models.Answer{}
+	answersResult := qc.DB.WithContext(ctx).Where("question_id IN ?", questionIds).Order("created_at ASC").Find(&answers)
+	if answersResult.Error != nil {
+		return nil, answersResult.Error
+	}
+
+	questionAnswers := map[uuid.UUID][]dtos.AnswerResponse{}
+	for _, answer := range answers {
+		questionAnswers[answer.QuestionID] = append(questionAnswers[answer.QuestionID], *dtos.GenerateAnswerResponse(&answer))
+	}
+
+	for _, question := range questions {
+		questionResponse := question.response()
+		if answers, ok := questionAnswers[question.QuestionID]; ok {
+			questionResponse.Answers = answers
+		}
+		questionsResponse = append(questionsResponse, *questionResponse)
+	}
+
+	return questionsResponse, nil
+}
+
+// filterQuestion gives the content to store and whether the question is approved right away
+// masked words are replaced and a question sent to moderation waits for the admin
+func filterQuestion(event *models.Event, content string, filtered services.WordFilterResult) (string, bool) {
+	approved := !event.Moderation
+	if !filtered.Hit() {
+		return content, approved
+	}
+
+	switch event.FilterAction {
+	case models.MaskFilter:
+		return filtered.Masked, approved
+	default:
+		return content, false
+	}
+}
+
+// how alike two questions have to be to count as duplicates, the trigram similarity goes from 0 to 1
+const duplicateSimilarity = 0.5
+
+// the most similar questions shown at once
+const maxSimilarQuestions = 5
+
+// similarQuestions finds the questions of the event whose content looks like the given content, the most similar first
+// participants are only shown approved questions, the event admin gets the pending ones too
+func similarQuestions(db *gorm.DB, eventId uuid.UUID, content string, exclude *uuid.UUID, isEventAdmin bool) ([]dtos.SimilarQuestionResponse, error) {
+	similar := []dtos.SimilarQuestionResponse{}
+
+	// the % operator is what uses the trigram index, it's looser than duplicateSimilarity
+	query := db.Model(&models.Question{}).
+		Select("question_id, content, likes_count, similarity(content, ?) AS similarity", content).
+		Where("event_id = ? AND content % ?", eventId, content).
+		Where("similarity(content, ?) >= ?", content, duplicateSimilarity)
+
+	if !isEventAdmin {
+		query = query.Where("approved = ?", true)
+	}
+	if exclude != nil {
+		query = query.Where("question_id <> ?", *exclude)
+	}
+
+	result := query.Order("similarity DESC").Limit(maxSimilarQuestions).Scan(&similar)
+	return similar, result.Error
+}
+
+// suggestSimilarQuestions sends the questions that look like a new question to its author only
+// the question is already saved, so a failure here is only logged
+func (qc *questionController) suggestSimilarQuestions(ctx context.Context, s *melody.Session, question *models.Question) {
+	similar, err := similarQuestions(qc.DB.WithContext(ctx), question.EventID, question.Content, &question.QuestionID, false)
+	if err != nil {
+		log.Println("similar questions err: ", err, " question: ", question.QuestionID)
+		return
+	}
+
+	if len(similar) == 0 {
+		return
+	}
+
+	s.Write(dtos.WebSocketRespondJson(dtos.Question, dtos.SimilarQuestionsType, map[string]any{
+		"question_id": question.QuestionID,
+		"similar":     similar,
+	}))
+}
+
+// saveRevision keeps an edit of the question in its history, the question already has its new content
+func saveRevision(db *gorm.DB, question *models.Question, previousContent string, editor models.Editor, editorId uuid.UUID) error {
+	revision := models.QuestionRevision{
+		QuestionID:      question.QuestionID,
+		PreviousContent: previousContent,
+		Content:         question.Content,
+		Editor:          editor,
+		EditorID:        editorId,
+		CreatedAt:       question.UpdatedAt,
+	}
+
+	return db.Create(&revision).Error
+}
+
+// visibleQuestions only lets the event admin see the moderation queue
+// everyone else sees the approved questions and their own pending questions
+func visibleQuestions(isEventAdmin bool, user dtos.User) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if isEventAdmin {
+			return db
+		}
+		return db.Where("approved = ? OR user_id = ?", true, user.ID)
+	}
+}
+
+// findAdminQuestion finds the question and checks that the admin on the session owns its event
+// it writes the error back to the session and returns false when the admin isn't allowed
+func (qc *questionController) findAdminQuestion(ctx context.Context, s *melody.Session, questionId uuid.UUID) (models.Question, bool) {
+	return findEventAdminQuestion(qc.DB.WithContext(ctx), s, dtos.Question, questionId)
+}
+
+// findEventAdminQuestion is findAdminQuestion for the other groups that act on questions
+func findEventAdminQuestion(db *gorm.DB, s *melody.Session, group dtos.WebSocketGroup, questionId uuid.UUID) (models.Question, bool) {
+	question := models.Question{}
+	questionResult := db.Preload("Event").Where("question_id = ?", questionId).First(&question)
+	if questionResult.Error != nil {
+		switch questionResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, group, dtos.QuestionNotFoundCode, "there is no question with the given id")
+		default:
+			dtos.WebSocketWriteError(s, group, dtos.InternalErrorCode, questionResult.Error.Error())
+		}
+		return question, false
+	}
+
+	if !isEventAdminSession(s, question.Event.AdminID) {
+		dtos.WebSocketWriteError(s, group, dtos.ForbiddenCode, "You're not allowed to access this endpoint")
+		return question, false
+	}
+
+	return question, true
+}
+
+// broadcastQuestion sends an approved question's message to the whole event room
+// messages about pending questions only go to the event admin and the author
+func (qc *questionController) broadcastQuestion(question *models.Question, adminId uuid.UUID, msg []byte) {
+	broadcastForQuestion(qc.Room, question, adminId, msg)
+}
+
+func broadcastForQuestion(room services.RoomService, question *models.Question, adminId uuid.UUID, msg []byte) {
+	if question.Approved {
+		room.Broadcast(question.EventID, msg)
+		return
+	}
+
+	room.BroadcastTo(question.EventID, msg, services.Audience{
+		AdminID: &adminId,
+		UserID:  &question.UserID,
+	})
+}
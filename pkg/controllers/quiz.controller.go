@@ -0,0 +1,836 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/internal/config"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/olahol/melody"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// a quiz is played in rounds the admin moves through one by one
+// every round is timed by the server, it ends when its time is up or when the admin moves on
+// a correct answer is worth half the points of the round, the other half shrinks with the time taken
+// when a round ends its correct options and a snapshot of the leaderboard are sent to the room
+// the round timers only live in the process, so on start the rounds left open are ended or timed again
+
+const (
+	// the time limit and points of a round when the admin doesn't give them
+	defaultQuizTimeLimit = 20
+	defaultQuizPoints    = 1000
+	// how many players are in the leaderboard sent after every round
+	quizLeaderboardSize = 10
+)
+
+type QuizController interface {
+	// http
+	GetEventQuizzes(ctx *gin.Context)
+	CreateQuiz(ctx *gin.Context)
+	NextRound(ctx *gin.Context)
+	FinishQuiz(ctx *gin.Context)
+	DeleteQuiz(ctx *gin.Context)
+	GetQuizResults(ctx *gin.Context)
+
+	// websocket
+	JoinQuiz(s *melody.Session, b []byte)
+	AnswerQuiz(s *melody.Session, b []byte)
+}
+
+type quizController struct {
+	DB   *gorm.DB
+	Room services.RoomService
+}
+
+func NewQuizController(db *gorm.DB, room services.RoomService) QuizController {
+	qc := &quizController{
+		DB:   db,
+		Room: room,
+	}
+
+	go qc.recoverRounds()
+
+	return qc
+}
+
+// http
+// GetEventQuizzes lists the quizzes of the event
+// participants only see the quizzes that started and the rounds that were played, the event admin sees everything
+func (qc *quizController) GetEventQuizzes(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	eventId := ctx.Param("event_id")
+
+	event := models.Event{}
+	eventResult := qc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	currentAdmin, isAdmin := ctx.Get("currentAdmin")
+	isEventAdmin := isAdmin && currentAdmin.(models.Admin).AdminID == event.AdminID
+
+	query := qc.DB.WithContext(dbTimeoutCtx).Scopes(preloadQuizRounds).Where("event_id = ?", event.EventID)
+	if !isEventAdmin {
+		query = query.Where("status <> ?", models.DraftQuiz)
+	}
+
+	quizzes := []models.Quiz{}
+	quizzesResult := query.Order("created_at ASC").Find(&quizzes)
+	if quizzesResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, quizzesResult.Error.Error())
+		return
+	}
+
+	quizzesResponse := []dtos.QuizResponse{}
+	for _, quiz := range quizzes {
+		quizResponse := dtos.GenerateQuizResponse(&quiz)
+
+		quizResponse.Rounds = []dtos.QuizRoundResponse{}
+		for _, round := range quiz.Rounds {
+			// the rounds to come stay hidden and the answers of a round until it ended
+			if !isEventAdmin && round.Position > quiz.CurrentRound {
+				continue
+			}
+			quizResponse.Rounds = append(quizResponse.Rounds, *dtos.GenerateQuizRoundResponse(&round, isEventAdmin || round.EndedAt != nil))
+		}
+
+		if quiz.Status == models.FinishedQuiz && len(quiz.Results) > 0 {
+			results := dtos.QuizLeaderboardResponse{}
+			if err := json.Unmarshal(quiz.Results, &results); err != nil {
+				dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+				return
+			}
+			quizResponse.Results = &results
+		}
+
+		quizzesResponse = append(quizzesResponse, *quizResponse)
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, quizzesResponse)
+}
+
+// CreateQuiz adds a draft quiz with all its rounds to the event
+func (qc *quizController) CreateQuiz(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	eventId := ctx.Param("event_id")
+	var payload dtos.CreateQuizInput
+
+	// try to bind the request body to the payload struct
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// get event by event_id
+	event := models.Event{}
+	eventResult := qc.DB.WithContext(dbTimeoutCtx).Where("event_id = ?", eventId).First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error.Error() {
+		case "record not found":
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// check if admin is the admin that created the event
+	if event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return
+	}
+
+	now := time.Now().UTC()
+	newQuiz := models.Quiz{
+		EventID:      event.EventID,
+		Title:        payload.Title,
+		Status:       models.DraftQuiz,
+		CurrentRound: -1,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	for i, roundInput := range payload.Rounds {
+		round := models.QuizRound{
+			Position:  i,
+			Question:  roundInput.Question,
+			TimeLimit: roundInput.TimeLimit,
+			Points:    roundInput.Points,
+		}
+		if round.TimeLimit == 0 {
+			round.TimeLimit = defaultQuizTimeLimit
+		}
+		if round.Points == 0 {
+			round.Points = defaultQuizPoints
+		}
+
+		hasCorrect := false
+		for j, optionInput := range roundInput.Options {
+			round.Options = append(round.Options, models.QuizOption{
+				Content:  optionInput.Content,
+				Correct:  optionInput.Correct,
+				Position: j,
+			})
+			hasCorrect = hasCorrect || optionInput.Correct
+		}
+
+		if !hasCorrect {
+			dtos.RespondWithError(ctx, http.StatusBadRequest, "every round needs at least one correct option")
+			return
+		}
+
+		newQuiz.Rounds = append(newQuiz.Rounds, round)
+	}
+
+	// the rounds and their options are created with the quiz
+	quizResult := qc.DB.WithContext(dbTimeoutCtx).Create(&newQuiz)
+	if quizResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, quizResult.Error.Error())
+		return
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusCreated, generateAdminQuizResponse(&newQuiz))
+}
+
+// NextRound ends the round being played and opens the next one, the first call starts the quiz
+func (qc *quizController) NextRound(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	quiz, ok := qc.findAdminQuiz(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	if quiz.Status == models.FinishedQuiz {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "this quiz has already finished")
+		return
+	}
+
+	next := quiz.CurrentRound + 1
+	if next >= len(quiz.Rounds) {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "there is no round left, finish the quiz")
+		return
+	}
+
+	tx := qc.DB.Begin()
+
+	// the current round in the where makes two admins clicking next at once move only one round
+	now := time.Now().UTC()
+	updateQuizResult := tx.WithContext(dbTimeoutCtx).Model(&models.Quiz{}).Where("quiz_id = ? AND current_round = ? AND status <> ?", quiz.QuizID, quiz.CurrentRound, models.FinishedQuiz).Updates(map[string]any{
+		"status":        models.RunningQuiz,
+		"current_round": next,
+		"started_at":    gorm.Expr("COALESCE(started_at, ?)", now),
+		"updated_at":    now,
+	})
+	if updateQuizResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, updateQuizResult.Error.Error())
+		return
+	}
+
+	if updateQuizResult.RowsAffected < 1 {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusConflict, "the quiz has already moved on")
+		return
+	}
+
+	round := quiz.Rounds[next]
+	closesAt := now.Add(time.Duration(round.TimeLimit) * time.Second)
+	updateRoundResult := tx.WithContext(dbTimeoutCtx).Model(&models.QuizRound{}).Where("round_id = ?", round.RoundID).Updates(map[string]any{
+		"opened_at": now,
+		"closes_at": closesAt,
+	})
+	if updateRoundResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, updateRoundResult.Error.Error())
+		return
+	}
+
+	// commit the transaction
+	if commitResult := tx.Commit(); commitResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, commitResult.Error.Error())
+		return
+	}
+
+	// the players get the results of the last round before the next one
+	if quiz.CurrentRound >= 0 {
+		qc.endRound(quiz.QuizID, quiz.Rounds[quiz.CurrentRound].RoundID)
+	}
+
+	round.OpenedAt = &now
+	round.ClosesAt = &closesAt
+	qc.scheduleRoundEnd(&round)
+
+	// the players get the clock of the server to count down the round in sync
+	roundResponse := dtos.GenerateQuizRoundResponse(&round, false)
+	roundResponse.ServerTime = &now
+	qc.Room.Broadcast(quiz.EventID, dtos.WebSocketRespondJson(dtos.Quiz, dtos.QuizRoundType, roundResponse))
+
+	dtos.RespondWithJson(ctx, http.StatusOK, dtos.GenerateQuizRoundResponse(&round, true))
+}
+
+// FinishQuiz ends the last round and keeps the final leaderboard with the quiz
+func (qc *quizController) FinishQuiz(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	quiz, ok := qc.findAdminQuiz(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	if quiz.Status != models.RunningQuiz {
+		dtos.RespondWithError(ctx, http.StatusBadRequest, "only a running quiz can be finished")
+		return
+	}
+
+	// the answers of the round being played still count
+	qc.endRound(quiz.QuizID, quiz.Rounds[quiz.CurrentRound].RoundID)
+
+	tx := qc.DB.Begin()
+
+	now := time.Now().UTC()
+	finishResult := tx.WithContext(dbTimeoutCtx).Model(&models.Quiz{}).Where("quiz_id = ? AND status = ?", quiz.QuizID, models.RunningQuiz).Updates(map[string]any{
+		"status":      models.FinishedQuiz,
+		"finished_at": now,
+		"updated_at":  now,
+	})
+	if finishResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, finishResult.Error.Error())
+		return
+	}
+
+	if finishResult.RowsAffected < 1 {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusConflict, "the quiz has already finished")
+		return
+	}
+
+	// the whole leaderboard is kept, not only the top of it
+	results, err := quizLeaderboard(tx.WithContext(dbTimeoutCtx), &quiz, 0)
+	if err != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	storeResult := tx.WithContext(dbTimeoutCtx).Model(&models.Quiz{}).Where("quiz_id = ?", quiz.QuizID).Update("results", string(dtos.EncodeJson(results)))
+	if storeResult.Error != nil {
+		tx.Rollback()
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, storeResult.Error.Error())
+		return
+	}
+
+	// commit the transaction
+	if commitResult := tx.Commit(); commitResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, commitResult.Error.Error())
+		return
+	}
+
+	quiz.Status = models.FinishedQuiz
+	quiz.FinishedAt = &now
+	quiz.UpdatedAt = now
+
+	// the room only gets the top of the leaderboard, the players load the whole one kept with the quiz from the quiz list
+	top := results
+	if len(top.Players) > quizLeaderboardSize {
+		top.Players = top.Players[:quizLeaderboardSize]
+	}
+	finishedResponse := dtos.GenerateQuizResponse(&quiz)
+	finishedResponse.Results = &top
+	qc.Room.Broadcast(quiz.EventID, dtos.WebSocketRespondJson(dtos.Quiz, dtos.QuizFinishedType, finishedResponse))
+
+	quizResponse := dtos.GenerateQuizResponse(&quiz)
+	quizResponse.Results = &results
+
+	dtos.RespondWithJson(ctx, http.StatusOK, quizResponse)
+}
+
+// DeleteQuiz removes the quiz with its rounds, players and answers
+func (qc *quizController) DeleteQuiz(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	quiz, ok := qc.findAdminQuiz(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	deleteQuizResult := qc.DB.WithContext(dbTimeoutCtx).Delete(&models.Quiz{}, "quiz_id = ?", quiz.QuizID)
+	if deleteQuizResult.Error != nil {
+		dtos.RespondWithError(ctx, http.StatusInternalServerError, deleteQuizResult.Error.Error())
+		return
+	}
+
+	// participants never saw a draft
+	if quiz.Status != models.DraftQuiz {
+		qc.Room.Broadcast(quiz.EventID, dtos.WebSocketRespondJson(dtos.Quiz, dtos.QuizDeletedType, map[string]any{
+			"quiz_id":  quiz.QuizID,
+			"event_id": quiz.EventID,
+		}))
+	}
+
+	dtos.RespondWithJson(ctx, http.StatusOK, "Successfully delete quiz")
+}
+
+// GetQuizResults gives the whole leaderboard of the quiz
+// a finished quiz gives the leaderboard kept when it finished
+func (qc *quizController) GetQuizResults(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	quiz, ok := qc.findAdminQuiz(ctx, dbTimeoutCtx)
+	if !ok {
+		return
+	}
+
+	results := dtos.QuizLeaderboardResponse{}
+	if quiz.Status == models.FinishedQuiz && len(quiz.Results) > 0 {
+		if err := json.Unmarshal(quiz.Results, &results); err != nil {
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else {
+		var err error
+		results, err = quizLeaderboard(qc.DB.WithContext(dbTimeoutCtx), &quiz, 0)
+		if err != nil {
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	quizResponse := generateAdminQuizResponse(&quiz)
+	quizResponse.Results = &results
+
+	dtos.RespondWithJson(ctx, http.StatusOK, quizResponse)
+}
+
+// websocket
+// JoinQuiz adds the user to the players of the quiz under a display name
+// joining again only changes the display name, a player who joins late gets the round being played
+func (qc *quizController) JoinQuiz(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	user := sessionUser(s)
+
+	var payload dtos.JoinQuizInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Quiz, b, &payload) {
+		return
+	}
+
+	quiz := models.Quiz{}
+	quizResult := qc.DB.WithContext(dbTimeoutCtx).Preload("Event").Scopes(preloadQuizRounds).Where("quiz_id = ?", payload.QuizID).First(&quiz)
+	if quizResult.Error != nil {
+		switch quizResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Quiz, dtos.QuizNotFoundCode, "there is no quiz with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InternalErrorCode, quizResult.Error.Error())
+		}
+		return
+	}
+
+	// participants never see a draft
+	if quiz.Status == models.DraftQuiz && !isEventAdminSession(s, quiz.Event.AdminID) {
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.QuizNotFoundCode, "there is no quiz with the given id")
+		return
+	}
+
+	if quiz.Status == models.FinishedQuiz {
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.QuizNotRunningCode, "this quiz has already finished")
+		return
+	}
+
+	player := models.QuizPlayer{
+		QuizID:      quiz.QuizID,
+		UserID:      user.ID,
+		DisplayName: payload.DisplayName,
+		JoinedAt:    time.Now().UTC(),
+	}
+	playerResult := qc.DB.WithContext(dbTimeoutCtx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "quiz_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"display_name"}),
+	}).Create(&player)
+	if playerResult.Error != nil {
+		log.Println(playerResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InternalErrorCode, playerResult.Error.Error())
+		return
+	}
+
+	joinResponse := map[string]any{
+		"quiz_id":      quiz.QuizID,
+		"display_name": player.DisplayName,
+	}
+
+	if quiz.Status == models.RunningQuiz {
+		if round := quiz.Rounds[quiz.CurrentRound]; round.EndedAt == nil {
+			now := time.Now().UTC()
+			roundResponse := dtos.GenerateQuizRoundResponse(&round, false)
+			roundResponse.ServerTime = &now
+			joinResponse["round"] = roundResponse
+		}
+	}
+
+	s.Write(dtos.WebSocketRespondJson(dtos.Quiz, dtos.JoinQuizType, joinResponse))
+}
+
+// AnswerQuiz takes the answer of a player to the round being played, every player answers a round once
+// the player isn't told if the answer was right until the round ends
+func (qc *quizController) AnswerQuiz(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	user := sessionUser(s)
+
+	var payload dtos.AnswerQuizInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Quiz, b, &payload) {
+		return
+	}
+
+	round := models.QuizRound{}
+	roundResult := qc.DB.WithContext(dbTimeoutCtx).Preload("Options").Where("round_id = ?", payload.RoundID).First(&round)
+	if roundResult.Error != nil {
+		switch roundResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Quiz, dtos.QuizNotFoundCode, "there is no round with the given id")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InternalErrorCode, roundResult.Error.Error())
+		}
+		return
+	}
+
+	quiz := models.Quiz{}
+	quizResult := qc.DB.WithContext(dbTimeoutCtx).Preload("Event").Where("quiz_id = ?", round.QuizID).First(&quiz)
+	if quizResult.Error != nil {
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InternalErrorCode, quizResult.Error.Error())
+		return
+	}
+
+	// quizzes can only be played while the event is live
+	if !checkEventLive(s, dtos.Quiz, &quiz.Event) {
+		return
+	}
+
+	if quiz.Status != models.RunningQuiz || quiz.CurrentRound != round.Position {
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.QuizRoundClosedCode, "this round isn't being played")
+		return
+	}
+
+	var option *models.QuizOption
+	for i := range round.Options {
+		if round.Options[i].OptionID == payload.OptionID {
+			option = &round.Options[i]
+		}
+	}
+
+	if option == nil {
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InvalidPayloadCode, "the option isn't an option of the round")
+		return
+	}
+
+	// start a transaction
+	// the share lock keeps the round open until the answer is in, ending the round waits for it
+	tx := qc.DB.Begin()
+
+	lockResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.Locking{Strength: "SHARE"}).Select("round_id", "closes_at", "ended_at").Where("round_id = ?", round.RoundID).First(&round)
+	if lockResult.Error != nil {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InternalErrorCode, lockResult.Error.Error())
+		return
+	}
+
+	// the time is taken on the server, the clock of the player doesn't matter
+	now := time.Now().UTC()
+	if round.EndedAt != nil || round.ClosesAt == nil || now.After(*round.ClosesAt) {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.QuizRoundClosedCode, "the time of this round is up")
+		return
+	}
+
+	answer := models.QuizAnswer{
+		RoundID:    round.RoundID,
+		UserID:     user.ID,
+		OptionID:   option.OptionID,
+		Correct:    option.Correct,
+		AnsweredAt: now,
+	}
+	if answer.Correct {
+		answer.Points = quizPoints(&round, now)
+	}
+
+	answerResult := tx.WithContext(dbTimeoutCtx).Clauses(clause.OnConflict{DoNothing: true}).Create(&answer)
+	if answerResult.Error != nil {
+		tx.Rollback()
+		log.Println(answerResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InternalErrorCode, answerResult.Error.Error())
+		return
+	}
+
+	if answerResult.RowsAffected < 1 {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.AlreadyAnsweredCode, "you already answered this round")
+		return
+	}
+
+	correctAnswers := 0
+	if answer.Correct {
+		correctAnswers = 1
+	}
+
+	// only the players who joined are scored
+	scoreResult := tx.WithContext(dbTimeoutCtx).Model(&models.QuizPlayer{}).Where("quiz_id = ? AND user_id = ?", quiz.QuizID, user.ID).Updates(map[string]any{
+		"score":           gorm.Expr("score + ?", answer.Points),
+		"correct_answers": gorm.Expr("correct_answers + ?", correctAnswers),
+	})
+	if scoreResult.Error != nil {
+		tx.Rollback()
+		log.Println(scoreResult.Error.Error())
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InternalErrorCode, scoreResult.Error.Error())
+		return
+	}
+
+	if scoreResult.RowsAffected < 1 {
+		tx.Rollback()
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.NotInQuizCode, "join the quiz before answering")
+		return
+	}
+
+	// commit the transaction
+	if commitResult := tx.Commit(); commitResult.Error != nil {
+		dtos.WebSocketWriteError(s, dtos.Quiz, dtos.InternalErrorCode, commitResult.Error.Error())
+		return
+	}
+
+	s.Write(dtos.WebSocketRespondJson(dtos.Quiz, dtos.AnswerQuizType, map[string]any{
+		"round_id":    answer.RoundID,
+		"option_id":   answer.OptionID,
+		"answered_at": answer.AnsweredAt,
+	}))
+}
+
+// endRound closes the round and sends its correct options and the leaderboard to the room
+// it runs when the time of the round is up and when the admin moves on, only the first one does anything
+func (qc *quizController) endRound(quizId uuid.UUID, roundId uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	// answers hold a share lock on the round, so this waits for the answers being given
+	endResult := qc.DB.WithContext(ctx).Model(&models.QuizRound{}).Where("round_id = ? AND ended_at IS NULL", roundId).Update("ended_at", time.Now().UTC())
+	if endResult.Error != nil {
+		log.Println("quiz round err: ", endResult.Error)
+		return
+	}
+
+	if endResult.RowsAffected < 1 {
+		return
+	}
+
+	quiz := models.Quiz{}
+	quizResult := qc.DB.WithContext(ctx).Where("quiz_id = ?", quizId).First(&quiz)
+	if quizResult.Error != nil {
+		log.Println("quiz round err: ", quizResult.Error)
+		return
+	}
+
+	round := models.QuizRound{}
+	roundResult := qc.DB.WithContext(ctx).Preload("Options", orderQuizOptions).Where("round_id = ?", roundId).First(&round)
+	if roundResult.Error != nil {
+		log.Println("quiz round err: ", roundResult.Error)
+		return
+	}
+
+	counts := []struct {
+		OptionID uuid.UUID
+		Answers  int64
+	}{}
+	countsResult := qc.DB.WithContext(ctx).Model(&models.QuizAnswer{}).Select("option_id, COUNT(*) AS answers").Where("round_id = ?", roundId).Group("option_id").Scan(&counts)
+	if countsResult.Error != nil {
+		log.Println("quiz round err: ", countsResult.Error)
+		return
+	}
+
+	answers := map[uuid.UUID]int64{}
+	for _, count := range counts {
+		answers[count.OptionID] = count.Answers
+	}
+
+	roundResponse := dtos.GenerateQuizRoundResponse(&round, true)
+	for i := range roundResponse.Options {
+		optionAnswers := answers[roundResponse.Options[i].OptionID]
+		roundResponse.Options[i].Answers = &optionAnswers
+	}
+	qc.Room.Broadcast(quiz.EventID, dtos.WebSocketRespondJson(dtos.Quiz, dtos.QuizRoundEndedType, roundResponse))
+
+	leaderboard, err := quizLeaderboard(qc.DB.WithContext(ctx), &quiz, quizLeaderboardSize)
+	if err != nil {
+		log.Println("quiz round err: ", err)
+		return
+	}
+	leaderboard.Round = round.Position
+	qc.Room.Broadcast(quiz.EventID, dtos.WebSocketRespondJson(dtos.Quiz, dtos.QuizLeaderboardType, leaderboard))
+}
+
+// scheduleRoundEnd ends the round when its time is up, a round whose time is already up is ended right away
+func (qc *quizController) scheduleRoundEnd(round *models.QuizRound) {
+	quizId, roundId := round.QuizID, round.RoundID
+	time.AfterFunc(time.Until(*round.ClosesAt), func() {
+		qc.endRound(quizId, roundId)
+	})
+}
+
+// recoverRounds times the rounds that were open when the app stopped
+// the rounds whose time ran out meanwhile are ended, every instance can do it since only the first end does anything
+func (qc *quizController) recoverRounds() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	rounds := []models.QuizRound{}
+	roundsResult := qc.DB.WithContext(ctx).Where("opened_at IS NOT NULL AND closes_at IS NOT NULL AND ended_at IS NULL").Find(&rounds)
+	if roundsResult.Error != nil {
+		log.Println("quiz round err: ", roundsResult.Error)
+		return
+	}
+
+	for i := range rounds {
+		qc.scheduleRoundEnd(&rounds[i])
+	}
+}
+
+// findAdminQuiz finds the quiz of the quiz_id param with its rounds and checks that the current admin owns its event
+// it responds with the error and returns false when the admin isn't allowed
+func (qc *quizController) findAdminQuiz(ctx *gin.Context, dbTimeoutCtx context.Context) (models.Quiz, bool) {
+	currentAdmin := ctx.MustGet("currentAdmin").(models.Admin)
+
+	quiz := models.Quiz{}
+	quizResult := qc.DB.WithContext(dbTimeoutCtx).Preload("Event").Scopes(preloadQuizRounds).Where("quiz_id = ?", ctx.Param("quiz_id")).First(&quiz)
+	if quizResult.Error != nil {
+		switch quizResult.Error {
+		case gorm.ErrRecordNotFound:
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no quiz with the given id")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, quizResult.Error.Error())
+		}
+		return quiz, false
+	}
+
+	// check if admin is the admin that created the event
+	if quiz.Event.AdminID != currentAdmin.AdminID {
+		dtos.RespondWithError(ctx, http.StatusUnauthorized, "You're not allowed to access this endpoint")
+		return quiz, false
+	}
+
+	return quiz, true
+}
+
+// generateAdminQuizResponse gives the quiz with every round and its correct options
+func generateAdminQuizResponse(quiz *models.Quiz) *dtos.QuizResponse {
+	quizResponse := dtos.GenerateQuizResponse(quiz)
+	for _, round := range quiz.Rounds {
+		quizResponse.Rounds = append(quizResponse.Rounds, *dtos.GenerateQuizRoundResponse(&round, true))
+	}
+	return quizResponse
+}
+
+// quizLeaderboard ranks the players of the quiz by their score, players with the same score share the rank
+// a limit of 0 gives every player
+func quizLeaderboard(db *gorm.DB, quiz *models.Quiz, limit int) (dtos.QuizLeaderboardResponse, error) {
+	leaderboard := dtos.QuizLeaderboardResponse{
+		QuizID:  quiz.QuizID,
+		EventID: quiz.EventID,
+		Round:   quiz.CurrentRound,
+		Players: []dtos.QuizPlayerResult{},
+	}
+
+	totalResult := db.Model(&models.QuizPlayer{}).Where("quiz_id = ?", quiz.QuizID).Count(&leaderboard.TotalPlayers)
+	if totalResult.Error != nil {
+		return leaderboard, totalResult.Error
+	}
+
+	query := db.Where("quiz_id = ?", quiz.QuizID).Order("score DESC, correct_answers DESC, joined_at ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	players := []models.QuizPlayer{}
+	playersResult := query.Find(&players)
+	if playersResult.Error != nil {
+		return leaderboard, playersResult.Error
+	}
+
+	leaderboard.Players = rankQuizPlayers(players)
+
+	return leaderboard, nil
+}
+
+// rankQuizPlayers ranks the players, who have to be sorted by their score already
+// players with the same score share the rank and the next player's rank counts every player before them
+func rankQuizPlayers(players []models.QuizPlayer) []dtos.QuizPlayerResult {
+	results := []dtos.QuizPlayerResult{}
+
+	for i, player := range players {
+		rank := i + 1
+		if i > 0 && player.Score == players[i-1].Score {
+			rank = results[i-1].Rank
+		}
+
+		results = append(results, dtos.QuizPlayerResult{
+			Rank:           rank,
+			UserID:         player.UserID,
+			DisplayName:    player.DisplayName,
+			Score:          player.Score,
+			CorrectAnswers: player.CorrectAnswers,
+		})
+	}
+
+	return results
+}
+
+// quizPoints gives the points of a correct answer given at the time
+// half of the points are for being correct, the other half goes down to nothing at the end of the round
+func quizPoints(round *models.QuizRound, answeredAt time.Time) int {
+	base := round.Points / 2
+
+	remaining := round.ClosesAt.Sub(answeredAt)
+	if remaining <= 0 {
+		return base
+	}
+
+	limit := time.Duration(round.TimeLimit) * time.Second
+	if remaining > limit {
+		remaining = limit
+	}
+
+	return base + int(float64(round.Points-base)*remaining.Seconds()/limit.Seconds())
+}
+
+// preloadQuizRounds loads the rounds of a quiz and their options in the order the admin gave them
+func preloadQuizRounds(db *gorm.DB) *gorm.DB {
+	return db.Preload("Rounds", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Rounds.Options", orderQuizOptions)
+}
+
+func orderQuizOptions(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/google/uuid"
+)
+
+func TestQuizPoints(t *testing.T) {
+	closesAt := time.Date(2023, 10, 5, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		points    int
+		timeLimit int
+		remaining time.Duration
+		want      int
+	}{
+		{"right away", 1000, 20, 20 * time.Second, 1000},
+		{"half the time left", 1000, 20, 10 * time.Second, 750},
+		{"a quarter of the time left", 1000, 20, 5 * time.Second, 625},
+		{"at the close", 1000, 20, 0, 500},
+		{"after the close", 1000, 20, -time.Second, 500},
+		{"more time left than the limit", 1000, 20, 30 * time.Second, 1000},
+		{"odd points", 101, 10, 5 * time.Second, 75},
+		{"odd points right away", 101, 10, 10 * time.Second, 101},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			round := &models.QuizRound{
+				Points:    tt.points,
+				TimeLimit: tt.timeLimit,
+				ClosesAt:  &closesAt,
+			}
+
+			if got := quizPoints(round, closesAt.Add(-tt.remaining)); got != tt.want {
+				t.Errorf("quizPoints() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankQuizPlayers(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []int
+		ranks  []int
+	}{
+		{"no players", []int{}, []int{}},
+		{"one player", []int{300}, []int{1}},
+		{"no ties", []int{300, 200, 100}, []int{1, 2, 3}},
+		{"tie at the top", []int{300, 300, 100}, []int{1, 1, 3}},
+		{"tie in the middle", []int{300, 200, 200, 200, 100}, []int{1, 2, 2, 2, 5}},
+		{"tie at the bottom", []int{300, 0, 0}, []int{1, 2, 2}},
+		{"everyone tied", []int{0, 0, 0}, []int{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players := []models.QuizPlayer{}
+			for i, score := range tt.scores {
+				players = append(players, models.QuizPlayer{
+					UserID:         uuid.New(),
+					DisplayName:    string(rune('a' + i)),
+					Score:          score,
+					CorrectAnswers: i,
+				})
+			}
+
+			results := rankQuizPlayers(players)
+			if len(results) != len(tt.ranks) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.ranks))
+			}
+
+			for i, result := range results {
+				if result.Rank != tt.ranks[i] {
+					t.Errorf("player %d: rank = %d, want %d", i, result.Rank, tt.ranks[i])
+				}
+				// the players keep their order and their data
+				if result.UserID != players[i].UserID || result.DisplayName != players[i].DisplayName || result.Score != players[i].Score || result.CorrectAnswers != players[i].CorrectAnswers {
+					t.Errorf("player %d: result = %+v, want %+v", i, result, players[i])
+				}
+			}
+		})
+	}
+}
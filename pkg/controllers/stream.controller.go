@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// the stream is a read-only server-sent events feed of an event room
+// for projector screens and networks that block websockets
+// every event carries the same json as the websocket messages and its room sequence as the event id
+// so a reconnecting client resumes with the Last-Event-ID header
+
+// how often a comment is sent to keep idle connections open through proxies
+const streamKeepAliveInterval = 15 * time.Second
+
+type StreamController interface {
+	StreamEvent(ctx *gin.Context)
+}
+
+type streamController struct {
+	DB                 *gorm.DB
+	QuestionController QuestionController
+	Room               services.RoomService
+}
+
+func NewStreamController(db *gorm.DB, questionController QuestionController, room services.RoomService) StreamController {
+	return &streamController{
+		DB:                 db,
+		QuestionController: questionController,
+		Room:               room,
+	}
+}
+
+// StreamEvent streams the messages of the event room meant for everyone
+// the event is given by its id or its event code
+func (sc *streamController) StreamEvent(ctx *gin.Context) {
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	// get event by event_id or event_code
+	event := models.Event{}
+	query := sc.DB.WithContext(dbTimeoutCtx)
+	if eventId, err := uuid.Parse(ctx.Param("event_id")); err == nil {
+		query = query.Where("event_id = ?", eventId)
+	} else {
+		query = query.Where("event_code = ?", ctx.Param("event_id"))
+	}
+
+	eventResult := query.First(&event)
+	if eventResult.Error != nil {
+		switch eventResult.Error.Error() {
+		case "record not found":
+			dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id or code")
+		default:
+			dtos.RespondWithError(ctx, http.StatusInternalServerError, eventResult.Error.Error())
+		}
+		return
+	}
+
+	// browsers send the header when they reconnect, the query is for the first connection
+	lastEventId := ctx.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = ctx.Query("last_event_id")
+	}
+
+	var lastSeq *uint64
+	if lastEventId != "" {
+		seq, err := strconv.ParseUint(lastEventId, 10, 64)
+		if err != nil {
+			dtos.RespondWithError(ctx, http.StatusBadRequest, "Last-Event-ID must be a sequence number")
+			return
+		}
+		lastSeq = &seq
+	}
+
+	// subscribe before catching up so nothing sent in between is lost
+	sub := sc.Room.Subscribe(event.EventID)
+	defer sc.Room.Unsubscribe(sub)
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	// the sequence the client is current to, messages up to it are skipped
+	var sent uint64
+
+	missed, ok := []services.RoomMessage{}, false
+	if lastSeq != nil {
+		missed, ok = sc.Room.Missed(event.EventID, *lastSeq)
+	}
+
+	if ok {
+		sent = *lastSeq
+		for _, message := range missed {
+			writeStreamEvent(ctx, message.Seq, message.Msg)
+			sent = message.Seq
+		}
+	} else {
+		// a new client, or one that missed too much, starts from a snapshot
+		snapshot, seq, err := sc.QuestionController.PublicSnapshot(dbTimeoutCtx, &event)
+		if err != nil {
+			writeStreamEvent(ctx, 0, dtos.WebSocketRespondErrorCode(dtos.Question, dtos.InternalErrorCode, err.Error()))
+			return
+		}
+		writeStreamEvent(ctx, seq, snapshot)
+		sent = seq
+	}
+	ctx.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+
+		case <-keepAlive.C:
+			fmt.Fprint(ctx.Writer, ": keep-alive\n\n")
+			ctx.Writer.Flush()
+
+		case message, ok := <-sub.C:
+			// the stream fell behind, the client reconnects and resumes from the last event id
+			if !ok {
+				return
+			}
+
+			if message.Seq <= sent {
+				continue
+			}
+
+			writeStreamEvent(ctx, message.Seq, message.Msg)
+			ctx.Writer.Flush()
+			sent = message.Seq
+		}
+	}
+}
+
+// writeStreamEvent writes one server-sent event, an event without a sequence doesn't move the client's last event id
+func writeStreamEvent(ctx *gin.Context, seq uint64, msg []byte) {
+	if seq > 0 {
+		fmt.Fprintf(ctx.Writer, "id: %d\n", seq)
+	}
+	fmt.Fprintf(ctx.Writer, "data: %s\n\n", msg)
+}
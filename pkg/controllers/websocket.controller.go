@@ -1,12 +1,23 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/internal/config"
 	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/middlewares"
+	"github.com/HudYuSa/mydeen/pkg/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/olahol/melody"
+	"gorm.io/gorm"
 )
 
 type WebSocketController interface {
@@ -14,25 +25,70 @@ type WebSocketController interface {
 	HandleConnect(s *melody.Session)
 	HandleDisconnect(s *melody.Session)
 	HandleMessage(s *melody.Session, b []byte)
+	JoinRoom(s *melody.Session, b []byte)
+	LeaveRoom(s *melody.Session, b []byte)
 }
 
 type webSocketController struct {
+	DB                 *gorm.DB
 	QuestionController QuestionController
 	LikeController     LikeController
+	AnswerController   AnswerController
+	PollController     PollController
+	QuizController     QuizController
+	Room               services.RoomService
+	RateLimiter        services.RateLimiter
 	Melody             *melody.Melody
 }
 
-func NewWebSocketController(questionController QuestionController, likeController LikeController, m *melody.Melody) WebSocketController {
+func NewWebSocketController(db *gorm.DB, questionController QuestionController, likeController LikeController, answerController AnswerController, pollController PollController, quizController QuizController, room services.RoomService, rateLimiter services.RateLimiter, m *melody.Melody) WebSocketController {
 	return &webSocketController{
+		DB:                 db,
 		QuestionController: questionController,
 		LikeController:     likeController,
+		AnswerController:   answerController,
+		PollController:     pollController,
+		QuizController:     quizController,
+		Room:               room,
+		RateLimiter:        rateLimiter,
 		Melody:             m,
 	}
 }
 
 // UpgradeCConnection upgrades an HTTP connection to a WebSocket
+// the client can bind the connection to an event with ?event_id= or ?event_code=
+// a reconnecting client adds ?last_seq= to get the messages it missed
 func (wsc *webSocketController) UpgradeConnection(ctx *gin.Context) {
-	// wsc.Melody.
+	dbTimeoutCtx := ctx.MustGet("dbTimeoutContext").(context.Context)
+
+	eventId := ctx.Query("event_id")
+	eventCode := ctx.Query("event_code")
+
+	if eventId != "" || eventCode != "" {
+		event, err := wsc.findRoomEvent(dbTimeoutCtx, eventId, eventCode)
+		if err != nil {
+			switch err {
+			case gorm.ErrRecordNotFound:
+				dtos.RespondWithError(ctx, http.StatusNotFound, "there is no event with the given id or code")
+			default:
+				dtos.RespondWithError(ctx, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		// the session joins this room in HandleConnect
+		ctx.Set("roomEvent", event)
+
+		if lastSeqQuery := ctx.Query("last_seq"); lastSeqQuery != "" {
+			lastSeq, err := strconv.ParseUint(lastSeqQuery, 10, 64)
+			if err != nil {
+				dtos.RespondWithError(ctx, http.StatusBadRequest, "last_seq has to be a positive number")
+				return
+			}
+			ctx.Set("lastSeq", lastSeq)
+		}
+	}
+
 	wsc.Melody.HandleRequest(ctx.Writer, ctx.Request.WithContext(ctx))
 }
 
@@ -40,60 +96,306 @@ func (wsc *webSocketController) UpgradeConnection(ctx *gin.Context) {
 func (wsc *webSocketController) HandleConnect(s *melody.Session) {
 	log.Println("new connection")
 	log.Println("connections: ", wsc.Melody.Len()+1)
+
+	// admins get to see their events' moderation queue
+	role := middlewares.WSSetAccount(s)
+	log.Println("role: ", role)
+
+	if event, ok := s.Request.Context().Value("roomEvent").(models.Event); ok {
+		var lastSeq *uint64
+		if value, ok := s.Request.Context().Value("lastSeq").(uint64); ok {
+			lastSeq = &value
+		}
+
+		wsc.joinRoom(s, &event, lastSeq)
+	}
 }
 
 // HandleDisconnect handles WebSocket Disconnections
 func (wsc *webSocketController) HandleDisconnect(s *melody.Session) {
 	log.Println("removed connection")
 	log.Println("connections: ", wsc.Melody.Len()-1)
+
+	wsc.Room.Leave(s)
+	wsc.RateLimiter.Forget(sessionRateKey(s))
 }
 
 // HandleMessage handles incoming Websocket messages.
 func (wsc *webSocketController) HandleMessage(s *melody.Session, b []byte) {
-	var msg map[string]interface{}
-	if err := json.Unmarshal(b, &msg); err != nil {
+	var command dtos.WebSocketCommand
+	if err := json.Unmarshal(b, &command); err != nil {
 		// handle decoding error
 		// write back to the websocket connection session
-		s.Write(dtos.EncodeJson(dtos.WebResponse{
-			Message: err.Error(),
-			Error:   true,
-		}))
+		dtos.WebSocketWriteError(s, "", dtos.InvalidPayloadCode, err.Error())
+		return
+	}
+
+	// every response of this command echoes its request id
+	dtos.SetWebSocketRequest(s, &dtos.WebSocketRequest{
+		ID:      command.RequestID,
+		Version: command.Version,
+	})
+	defer dtos.ClearWebSocketRequest(s)
+
+	if command.Version >= dtos.WebSocketVersion2 && command.RequestID == "" {
+		dtos.WebSocketWriteError(s, "", dtos.MissingRequestIDCode, "request_id is required")
+		return
+	}
+
+	// every message is counted against the budget of the socket and of the participant behind it
+	if allowed, wait := wsc.RateLimiter.Allow(command.Type, sessionRateKey(s), "user:"+sessionUser(s).ID.String()); !allowed {
+		dtos.WebSocketWriteError(s, "", dtos.RateLimitedCode, fmt.Sprintf("too many messages, try again in %s", wait.Round(time.Millisecond)))
+		return
 	}
 
 	log.Println("new message")
-	log.Println(msg)
+	log.Println(command.Type, command.RequestID)
 
 	// Handle different message types
-	switch msg["type"] {
+	switch command.Type {
+	// rooms message
+	case dtos.JoinRoomType:
+		log.Println("entering join room type")
+		wsc.JoinRoom(s, b)
+
+	case dtos.LeaveRoomType:
+		log.Println("entering leave room type")
+		wsc.LeaveRoom(s, b)
+
 	// questions message
-	case string(dtos.CreateQuestionType):
+	case dtos.CreateQuestionType:
 		log.Println("entering create question type")
 		wsc.QuestionController.CreateQuestion(s, b)
 
-	case string(dtos.DeleteQuestionType):
+	case dtos.DeleteQuestionType:
 		log.Println("entering delete question type")
-		// example of using middleware
-		// if middlewares.WSAuthenticateAdmin(s, dtos.Question) {
-		// 	wsc.QuestionController.DeleteQuestion(s, b)
-		// }
 		wsc.QuestionController.DeleteQuestion(s, b)
 
-	case string(dtos.EditQuestionType):
+	case dtos.EditQuestionType:
 		log.Println("entering edit question type")
 		wsc.QuestionController.EditQuestion(s, b)
 
-	case string(dtos.AdminDeleteQuestionType):
+	// admin questions message
+	case dtos.AdminDeleteQuestionType:
 		log.Println("entering admin delete question type")
-		// not implemented
+		if middlewares.WSAuthenticateAdmin(s, dtos.Question) {
+			wsc.QuestionController.AdminDeleteQuestion(s, b)
+		}
 
-	case string(dtos.AdminEditQuestionType):
+	case dtos.AdminEditQuestionType:
 		log.Println("entering admin edit question type")
-		// not implemented
+		if middlewares.WSAuthenticateAdmin(s, dtos.Question) {
+			wsc.QuestionController.AdminEditQuestion(s, b)
+		}
+
+	case dtos.StarQuestionType:
+		log.Println("entering star question type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Question) {
+			wsc.QuestionController.StarQuestion(s, b)
+		}
+
+	case dtos.AnswerQuestionType:
+		log.Println("entering answer question type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Question) {
+			wsc.QuestionController.AnswerQuestion(s, b)
+		}
+
+	case dtos.MergeQuestionsType:
+		log.Println("entering merge questions type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Question) {
+			wsc.QuestionController.MergeQuestions(s, b)
+		}
+
+	case dtos.ApproveQuestionType:
+		log.Println("entering approve question type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Question) {
+			wsc.QuestionController.ApproveQuestion(s, b)
+		}
+
+	case dtos.RejectQuestionType:
+		log.Println("entering reject question type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Question) {
+			wsc.QuestionController.RejectQuestion(s, b)
+		}
+
+	// admin answers message
+	case dtos.CreateAnswerType:
+		log.Println("entering create answer type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Answer) {
+			wsc.AnswerController.CreateAnswer(s, b)
+		}
+
+	case dtos.EditAnswerType:
+		log.Println("entering edit answer type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Answer) {
+			wsc.AnswerController.EditAnswer(s, b)
+		}
+
+	case dtos.DeleteAnswerType:
+		log.Println("entering delete answer type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Answer) {
+			wsc.AnswerController.DeleteAnswer(s, b)
+		}
 
 		// likes message
-	case string(dtos.ToggleLikeType):
+	case dtos.ToggleLikeType:
 		log.Println("entering toggle like type")
 		wsc.LikeController.ToggleLike(s, b)
+
+	// polls message
+	case dtos.VotePollType:
+		log.Println("entering vote poll type")
+		wsc.PollController.VotePoll(s, b)
+
+	// admin polls message
+	case dtos.ApprovePollAnswerType:
+		log.Println("entering approve poll answer type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Poll) {
+			wsc.PollController.ApprovePollAnswer(s, b)
+		}
+
+	case dtos.RejectPollAnswerType:
+		log.Println("entering reject poll answer type")
+		if middlewares.WSAuthenticateAdmin(s, dtos.Poll) {
+			wsc.PollController.RejectPollAnswer(s, b)
+		}
+
+	// quizzes message
+	case dtos.JoinQuizType:
+		log.Println("entering join quiz type")
+		wsc.QuizController.JoinQuiz(s, b)
+
+	case dtos.AnswerQuizType:
+		log.Println("entering answer quiz type")
+		wsc.QuizController.AnswerQuiz(s, b)
+
+	default:
+		dtos.WebSocketWriteError(s, "", dtos.UnknownTypeCode, fmt.Sprintf("unknown message type %q", command.Type))
+		return
 	}
 
+	// the command didn't fail, acknowledge it
+	dtos.WebSocketWriteAck(s, command.Type)
+}
+
+// JoinRoom moves the session into the room of another event without reconnecting
+func (wsc *webSocketController) JoinRoom(s *melody.Session, b []byte) {
+	// dbtimeoutctx for websocket
+	dbTimeoutCtx, cancel := context.WithTimeout(s.Request.Context(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var payload dtos.JoinRoomInput
+
+	if !dtos.WebSocketBindJson(s, dtos.Room, b, &payload) {
+		return
+	}
+
+	if payload.EventID == "" && payload.EventCode == "" {
+		dtos.WebSocketWriteError(s, dtos.Room, dtos.InvalidPayloadCode, "event_id or event_code is required")
+		return
+	}
+
+	event, err := wsc.findRoomEvent(dbTimeoutCtx, payload.EventID, payload.EventCode)
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			dtos.WebSocketWriteError(s, dtos.Room, dtos.EventNotFoundCode, "there is no event with the given id or code")
+		default:
+			dtos.WebSocketWriteError(s, dtos.Room, dtos.InvalidPayloadCode, err.Error())
+		}
+		return
+	}
+
+	wsc.joinRoom(s, &event, payload.LastSeq)
+}
+
+// LeaveRoom removes the session from its event room, the connection stays open
+func (wsc *webSocketController) LeaveRoom(s *melody.Session, b []byte) {
+	eventId, ok := wsc.Room.EventID(s)
+	if !ok {
+		dtos.WebSocketWriteError(s, dtos.Room, dtos.NotInRoomCode, "you haven't joined any event")
+		return
+	}
+
+	wsc.Room.Leave(s)
+
+	s.Write(dtos.WebSocketRespondJson(dtos.Room, dtos.LeaveRoomType, map[string]any{
+		"event_id": eventId,
+	}))
+}
+
+// joinRoom binds the session to the event room and catches it up when it's reconnecting
+// the join response and the replay are written before any live message of the room
+func (wsc *webSocketController) joinRoom(s *melody.Session, event *models.Event, lastSeq *uint64) {
+	// the join response carries the sequence the session starts at so the client knows where it is
+	caughtUp := wsc.Room.Join(s, event.EventID, lastSeq, func(seq uint64) []byte {
+		return dtos.SetWebSocketSeq(dtos.WebSocketRespondJson(dtos.Room, dtos.JoinRoomType, dtos.GenerateEventResponse(event)), seq)
+	})
+
+	// send the whole list when too much was missed to replay
+	if !caughtUp {
+		wsc.QuestionController.QuestionSnapshot(s, event)
+	}
+}
+
+// findRoomEvent looks up the event by id, or by code when there's no id
+func (wsc *webSocketController) findRoomEvent(ctx context.Context, eventId string, eventCode string) (models.Event, error) {
+	event := models.Event{}
+
+	query := wsc.DB.WithContext(ctx)
+	if eventId != "" {
+		if _, err := uuid.Parse(eventId); err != nil {
+			return event, gorm.ErrRecordNotFound
+		}
+		query = query.Where("event_id = ?", eventId)
+	} else {
+		query = query.Where("event_code = ?", eventCode)
+	}
+
+	eventResult := query.First(&event)
+	return event, eventResult.Error
+}
+
+// sessionRateKey identifies the session in the rate limiter
+func sessionRateKey(s *melody.Session) string {
+	return fmt.Sprintf("session:%p", s)
+}
+
+// sessionUser returns the anonymous user the session was opened with
+func sessionUser(s *melody.Session) dtos.User {
+	user, _ := s.Request.Context().Value("user").(dtos.User)
+	return user
+}
+
+// sessionAdmin returns the admin set on the session by the websocket admin middlewares
+func sessionAdmin(s *melody.Session) (models.Admin, bool) {
+	value, exists := s.Get("currentAdmin")
+	if !exists {
+		return models.Admin{}, false
+	}
+
+	admin, ok := value.(models.Admin)
+	return admin, ok
+}
+
+// isEventAdminSession checks if the session belongs to the admin with the given id
+func isEventAdminSession(s *melody.Session, adminId uuid.UUID) bool {
+	admin, ok := sessionAdmin(s)
+	return ok && admin.AdminID == adminId
+}
+
+// checkEventLive only lets participants write to an event while it's live
+// it writes the error back to the session and returns false when the event isn't live
+func checkEventLive(s *melody.Session, group dtos.WebSocketGroup, event *models.Event) bool {
+	switch event.Status {
+	case models.Live:
+		return true
+	case models.Scheluded:
+		dtos.WebSocketWriteError(s, group, dtos.EventNotLiveCode, "this event hasn't started yet")
+	case models.Finished:
+		dtos.WebSocketWriteError(s, group, dtos.EventNotLiveCode, "this event has already finished")
+	default:
+		dtos.WebSocketWriteError(s, group, dtos.EventNotLiveCode, "this event isn't live")
+	}
+	return false
 }
@@ -0,0 +1,52 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/google/uuid"
+)
+
+type AnswerResponse struct {
+	AnswerID   *uuid.UUID `json:"answer_id,omitempty"`
+	QuestionID *uuid.UUID `json:"question_id,omitempty"`
+	AdminID    *uuid.UUID `json:"admin_id,omitempty"`
+	Content    string     `json:"content,omitempty"`
+	Link       string     `json:"link,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// an answer is a short text, a link, or both
+// the link is shown to every participant so it can only be an http or https link
+type CreateAnswerInput struct {
+	QuestionID uuid.UUID `json:"question_id" binding:"required"`
+	Content    string    `json:"content" binding:"required_without=Link,max=2000"`
+	Link       string    `json:"link" binding:"omitempty,http_url,max=2048"`
+}
+
+type EditAnswerInput struct {
+	AnswerID uuid.UUID `json:"answer_id" binding:"required"`
+	Content  string    `json:"content" binding:"required_without=Link,max=2000"`
+	Link     string    `json:"link" binding:"omitempty,http_url,max=2048"`
+}
+
+type DeleteAnswerInput struct {
+	AnswerID uuid.UUID `json:"answer_id" binding:"required"`
+}
+
+func GenerateAnswerResponse(answer *models.Answer) *AnswerResponse {
+	if answer == nil {
+		return nil
+	}
+
+	return &AnswerResponse{
+		AnswerID:   CheckNil(answer.AnswerID),
+		QuestionID: CheckNil(answer.QuestionID),
+		AdminID:    CheckNil(answer.AdminID),
+		Content:    answer.Content,
+		Link:       answer.Link,
+		CreatedAt:  CheckNil(answer.CreatedAt),
+		UpdatedAt:  CheckNil(answer.UpdatedAt),
+	}
+}
@@ -0,0 +1,43 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/google/uuid"
+)
+
+type CategoryResponse struct {
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	EventID    *uuid.UUID `json:"event_id,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Position   int        `json:"position"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+// how many questions of an event are in a category, the questions without a category have no category id
+type CategoryCountResponse struct {
+	CategoryID     *uuid.UUID `json:"category_id"`
+	Name           string     `json:"name"`
+	QuestionsCount int64      `json:"questions_count"`
+	PendingCount   int64      `json:"pending_count"`
+	AnsweredCount  int64      `json:"answered_count"`
+}
+
+type CreateCategoryInput struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+func GenerateCategoryResponse(category *models.QuestionCategory) *CategoryResponse {
+	if category == nil {
+		return nil
+	}
+
+	return &CategoryResponse{
+		CategoryID: CheckNil(category.CategoryID),
+		EventID:    CheckNil(category.EventID),
+		Name:       category.Name,
+		Position:   category.Position,
+		CreatedAt:  CheckNil(category.CreatedAt),
+	}
+}
@@ -5,19 +5,39 @@ import (
 	"errors"
 	"log"
 	"reflect"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/olahol/melody"
 )
 
 // this package is where u put all data transfer object representation
 // and all its function
 
+// the role of whoever is behind a request or a websocket session
+type Role string
+
+const (
+	ParticipantRole Role = "participant"
+	AdminRole       Role = "admin"
+	MasterRole      Role = "master"
+)
+
 // this is for the message group of which the message are send to
 type WebSocketGroup string
 
 const (
 	Question WebSocketGroup = "question"
 	Like     WebSocketGroup = "like"
+	Room     WebSocketGroup = "room"
+	Event    WebSocketGroup = "event"
+	Answer   WebSocketGroup = "answer"
+	Poll     WebSocketGroup = "poll"
+	Quiz     WebSocketGroup = "quiz"
+	Category WebSocketGroup = "category"
 )
 
 // this is for the type of server response of the message
@@ -30,12 +50,125 @@ const (
 	EditQuestionType        WebSocketType = "editQuestion"
 	AdminDeleteQuestionType WebSocketType = "adminDeleteQuestion"
 	AdminEditQuestionType   WebSocketType = "adminEdit"
+	PendingQuestionType     WebSocketType = "pendingQuestion"
+	ApproveQuestionType     WebSocketType = "approveQuestion"
+	RejectQuestionType      WebSocketType = "rejectQuestion"
+	StarQuestionType        WebSocketType = "starQuestion"
+	AnswerQuestionType      WebSocketType = "answerQuestion"
+	SimilarQuestionsType    WebSocketType = "similarQuestions"
+	MergeQuestionsType      WebSocketType = "mergeQuestions"
+
+	// answers type
+	CreateAnswerType WebSocketType = "createAnswer"
+	EditAnswerType   WebSocketType = "editAnswer"
+	DeleteAnswerType WebSocketType = "deleteAnswer"
+
+	// polls type
+	VotePollType    WebSocketType = "votePoll"
+	PollOpenedType  WebSocketType = "pollOpened"
+	PollClosedType  WebSocketType = "pollClosed"
+	PollDeletedType WebSocketType = "pollDeleted"
+	PollResultsType WebSocketType = "pollResults"
+
+	// admin polls type
+	PendingPollAnswerType WebSocketType = "pendingPollAnswer"
+	ApprovePollAnswerType WebSocketType = "approvePollAnswer"
+	RejectPollAnswerType  WebSocketType = "rejectPollAnswer"
+
+	// quizzes type
+	JoinQuizType        WebSocketType = "joinQuiz"
+	AnswerQuizType      WebSocketType = "answerQuiz"
+	QuizRoundType       WebSocketType = "quizRound"
+	QuizRoundEndedType  WebSocketType = "quizRoundEnded"
+	QuizLeaderboardType WebSocketType = "quizLeaderboard"
+	QuizFinishedType    WebSocketType = "quizFinished"
+	QuizDeletedType     WebSocketType = "quizDeleted"
+
+	// categories type
+	CreateCategoryType WebSocketType = "createCategory"
+	DeleteCategoryType WebSocketType = "deleteCategory"
 
 	// likes type
 	ToggleLikeType WebSocketType = "toggleLike"
+	LikeCountsType WebSocketType = "likeCounts"
+
+	// rooms type
+	JoinRoomType  WebSocketType = "joinRoom"
+	LeaveRoomType WebSocketType = "leaveRoom"
+	PresenceType  WebSocketType = "presence"
+	SnapshotType  WebSocketType = "snapshot"
+
+	// events type
+	EventStatusType                   WebSocketType = "eventStatus"
+	EventNameUpdatedType              WebSocketType = "eventNameUpdated"
+	EventDateUpdatedType              WebSocketType = "eventDateUpdated"
+	EventModerationUpdatedType        WebSocketType = "eventModerationUpdated"
+	EventMaxQuestionLengthUpdatedType WebSocketType = "eventMaxQuestionLengthUpdated"
+	EventMaxQuestionsUpdatedType      WebSocketType = "eventMaxQuestionsUpdated"
+	EventSlowModeUpdatedType          WebSocketType = "eventSlowModeUpdated"
+	EventLockEditsUpdatedType         WebSocketType = "eventLockEditsUpdated"
+	EventRequireCategoryUpdatedType   WebSocketType = "eventRequireCategoryUpdated"
 
 	// error type
 	ErrorType WebSocketType = "error"
+
+	// ack type, sent when a command of a versioned client succeeded
+	AckType WebSocketType = "ack"
+)
+
+// this is the version of the websocket protocol
+// version 1 clients don't send a request id and only get error frames
+// version 2 clients send a request id and get exactly one ack or error frame for every command
+const (
+	WebSocketVersion1 = 1
+	WebSocketVersion2 = 2
+)
+
+// this is for the machine readable code of an error response
+type WebSocketErrorCode string
+
+const (
+	// common error code
+	InvalidPayloadCode   WebSocketErrorCode = "invalidPayload"
+	ValidationFailedCode WebSocketErrorCode = "validationFailed"
+	MissingRequestIDCode WebSocketErrorCode = "missingRequestId"
+	UnknownTypeCode      WebSocketErrorCode = "unknownType"
+	UnauthorizedCode     WebSocketErrorCode = "unauthorized"
+	ForbiddenCode        WebSocketErrorCode = "forbidden"
+	InvalidStateCode     WebSocketErrorCode = "invalidState"
+	InternalErrorCode    WebSocketErrorCode = "internalError"
+	RateLimitedCode      WebSocketErrorCode = "rateLimited"
+
+	// rooms error code
+	NotInRoomCode WebSocketErrorCode = "notInRoom"
+
+	// questions error code
+	QuestionNotFoundCode     WebSocketErrorCode = "questionNotFound"
+	AnswerNotFoundCode       WebSocketErrorCode = "answerNotFound"
+	QuestionTooLongCode      WebSocketErrorCode = "questionTooLong"
+	QuestionLimitReachedCode WebSocketErrorCode = "questionLimitReached"
+	SlowModeCode             WebSocketErrorCode = "slowMode"
+	ContentBlockedCode       WebSocketErrorCode = "contentBlocked"
+	QuestionLockedCode       WebSocketErrorCode = "questionLocked"
+	CategoryNotFoundCode     WebSocketErrorCode = "categoryNotFound"
+	CategoryRequiredCode     WebSocketErrorCode = "categoryRequired"
+
+	// polls error code
+	PollNotFoundCode       WebSocketErrorCode = "pollNotFound"
+	PollNotOpenCode        WebSocketErrorCode = "pollNotOpen"
+	AlreadyVotedCode       WebSocketErrorCode = "alreadyVoted"
+	PollAnswerNotFoundCode WebSocketErrorCode = "pollAnswerNotFound"
+
+	// quizzes error code
+	QuizNotFoundCode    WebSocketErrorCode = "quizNotFound"
+	QuizNotRunningCode  WebSocketErrorCode = "quizNotRunning"
+	NotInQuizCode       WebSocketErrorCode = "notInQuiz"
+	QuizRoundClosedCode WebSocketErrorCode = "quizRoundClosed"
+	AlreadyAnsweredCode WebSocketErrorCode = "alreadyAnswered"
+
+	// events error code
+	EventNotFoundCode WebSocketErrorCode = "eventNotFound"
+	EventNotLiveCode  WebSocketErrorCode = "eventNotLive"
 )
 
 type WebResponse struct {
@@ -45,13 +178,43 @@ type WebResponse struct {
 }
 
 type WebsocketResponse struct {
-	Type    WebSocketType  `json:"type,omitempty"`
-	Group   WebSocketGroup `json:"group,omitempty"`
-	Data    any            `json:"data,omitempty"`
-	Error   bool           `json:"error"`
-	Message string         `json:"message,omitempty"`
+	Version   int                `json:"version,omitempty"`
+	RequestID string             `json:"request_id,omitempty"`
+	Seq       uint64             `json:"seq,omitempty"`
+	Type      WebSocketType      `json:"type,omitempty"`
+	Group     WebSocketGroup     `json:"group,omitempty"`
+	Data      any                `json:"data,omitempty"`
+	Error     bool               `json:"error"`
+	Code      WebSocketErrorCode `json:"code,omitempty"`
+	Message   string             `json:"message,omitempty"`
+	Fields    []FieldError       `json:"fields,omitempty"`
 }
 
+// a field of a payload that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// every command a client sends over the websocket starts with this envelope
+type WebSocketCommand struct {
+	Type      WebSocketType `json:"type"`
+	RequestID string        `json:"request_id"`
+	Version   int           `json:"version"`
+}
+
+// WebSocketRequest is the command a session is handling right now
+// it's kept on the session while the command runs so every response can echo the request id
+type WebSocketRequest struct {
+	ID        string
+	Version   int
+	Responded bool
+}
+
+const webSocketRequestKey = "webSocketRequest"
+
 func RespondWithError(ctx *gin.Context, code int, errMsg string) {
 	err := errors.New(errMsg)
 
@@ -104,6 +267,258 @@ func WebSocketRespondError(group WebSocketGroup, errMsg string) []byte {
 	})
 }
 
+func WebSocketRespondErrorCode(group WebSocketGroup, code WebSocketErrorCode, errMsg string) []byte {
+	return EncodeJson(WebsocketResponse{
+		Type:    ErrorType,
+		Group:   group,
+		Error:   true,
+		Code:    code,
+		Message: errMsg,
+	})
+}
+
+// SetWebSocketSeq stamps the room sequence number on an encoded websocket response
+func SetWebSocketSeq(msg []byte, seq uint64) []byte {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &response); err != nil {
+		log.Println("json seq err: ", err)
+		return msg
+	}
+
+	response["seq"] = EncodeJson(seq)
+	return EncodeJson(response)
+}
+
+func SetWebSocketRequest(s *melody.Session, request *WebSocketRequest) {
+	s.Set(webSocketRequestKey, request)
+}
+
+func ClearWebSocketRequest(s *melody.Session) {
+	s.UnSet(webSocketRequestKey)
+}
+
+func getWebSocketRequest(s *melody.Session) *WebSocketRequest {
+	value, exists := s.Get(webSocketRequestKey)
+	if !exists {
+		return nil
+	}
+
+	request, _ := value.(*WebSocketRequest)
+	return request
+}
+
+// WebSocketWriteError writes the error frame of the command the session is handling
+// only the first response of a command is written
+func WebSocketWriteError(s *melody.Session, group WebSocketGroup, code WebSocketErrorCode, errMsg string) {
+	writeWebSocketError(s, WebsocketResponse{
+		Type:    ErrorType,
+		Group:   group,
+		Error:   true,
+		Code:    code,
+		Message: errMsg,
+	})
+}
+
+func writeWebSocketError(s *melody.Session, response WebsocketResponse) {
+	if request := getWebSocketRequest(s); request != nil {
+		if request.Responded {
+			return
+		}
+		request.Responded = true
+
+		response.RequestID = request.ID
+		if request.Version >= WebSocketVersion2 {
+			response.Version = request.Version
+		}
+	}
+
+	s.Write(EncodeJson(response))
+}
+
+// WebSocketBindJson decodes the payload of a websocket message into obj
+// and validates it with the binding tags, the same way gin binds http requests
+// it writes the error frame and returns false when the payload is invalid
+func WebSocketBindJson(s *melody.Session, group WebSocketGroup, b []byte, obj any) bool {
+	if err := json.Unmarshal(b, obj); err != nil {
+		response := WebsocketResponse{
+			Type:    ErrorType,
+			Group:   group,
+			Error:   true,
+			Code:    InvalidPayloadCode,
+			Message: err.Error(),
+		}
+
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			response.Fields = []FieldError{{
+				Field:   typeErr.Field,
+				Rule:    "type",
+				Param:   typeErr.Type.String(),
+				Message: typeErr.Field + " must be a " + typeErr.Type.String(),
+			}}
+		} else if fields := uuidFieldErrors(obj, b); len(fields) > 0 {
+			response.Message = fields[0].Message
+			response.Fields = fields
+		}
+
+		writeWebSocketError(s, response)
+		return false
+	}
+
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			WebSocketWriteError(s, group, InvalidPayloadCode, err.Error())
+			return false
+		}
+
+		fields := []FieldError{}
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, generateFieldError(obj, fieldErr))
+		}
+
+		writeWebSocketError(s, WebsocketResponse{
+			Type:    ErrorType,
+			Group:   group,
+			Error:   true,
+			Code:    ValidationFailedCode,
+			Message: fields[0].Message,
+			Fields:  fields,
+		})
+		return false
+	}
+
+	return true
+}
+
+// uuidFieldErrors finds the uuid fields of obj that got something that isn't a uuid in the payload
+// json reports a bad uuid without the field it was in, so the payload is read again to name the fields
+func uuidFieldErrors(obj any, b []byte) []FieldError {
+	objType := reflect.TypeOf(obj)
+	for objType.Kind() == reflect.Pointer {
+		objType = objType.Elem()
+	}
+	if objType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil
+	}
+
+	fields := []FieldError{}
+	for i := 0; i < objType.NumField(); i++ {
+		field := objType.Field(i)
+
+		// a uuid, a pointer to one or a list of them
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType != reflect.TypeOf(uuid.UUID{}) {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		value, ok := payload[name]
+		if !ok {
+			continue
+		}
+
+		if err := json.Unmarshal(value, reflect.New(field.Type).Interface()); err != nil {
+			fields = append(fields, FieldError{
+				Field:   name,
+				Rule:    "uuid",
+				Message: name + " must be a valid uuid",
+			})
+		}
+	}
+
+	return fields
+}
+
+// generateFieldError names the field after its json key so the client can match it to its input
+func generateFieldError(obj any, fieldErr validator.FieldError) FieldError {
+	name := fieldErr.Field()
+
+	objType := reflect.TypeOf(obj)
+	for objType.Kind() == reflect.Pointer {
+		objType = objType.Elem()
+	}
+	if objType.Kind() == reflect.Struct {
+		if field, ok := objType.FieldByName(fieldErr.StructField()); ok {
+			if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+				name = tag
+			}
+		}
+	}
+
+	var message string
+	switch fieldErr.Tag() {
+	case "required":
+		message = name + " is required"
+	case "uuid":
+		message = name + " must be a valid uuid"
+	case "max":
+		message = name + " must be at most " + fieldErr.Param() + limitUnit(fieldErr.Kind())
+	case "min":
+		message = name + " must be at least " + fieldErr.Param() + limitUnit(fieldErr.Kind())
+	case "oneof":
+		message = name + " must be one of " + fieldErr.Param()
+	case "http_url":
+		message = name + " must be an http or https link"
+	default:
+		message = name + " failed the " + fieldErr.Tag() + " rule"
+	}
+
+	return FieldError{
+		Field:   name,
+		Rule:    fieldErr.Tag(),
+		Param:   fieldErr.Param(),
+		Message: message,
+	}
+}
+
+// limitUnit is what a min or max of a field of the kind counts, a number is compared as it is
+func limitUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
+
+// WebSocketWriteAck acknowledges the command the session is handling
+// version 1 clients never get an ack, and a command that already failed doesn't get one either
+func WebSocketWriteAck(s *melody.Session, t WebSocketType) {
+	request := getWebSocketRequest(s)
+	if request == nil || request.Responded || request.Version < WebSocketVersion2 {
+		return
+	}
+	request.Responded = true
+
+	s.Write(EncodeJson(WebsocketResponse{
+		Version:   request.Version,
+		RequestID: request.ID,
+		Type:      AckType,
+		Data: map[string]any{
+			"type": t,
+		},
+		Error: false,
+	}))
+}
+
 func CheckNil[t any](anyType t) *t {
 	if reflect.ValueOf(anyType).IsZero() {
 		return nil
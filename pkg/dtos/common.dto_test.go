@@ -0,0 +1,139 @@
+package dtos
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type fieldErrorInput struct {
+	EventID  string `json:"event_id" binding:"required,uuid"`
+	Title    string `json:"title,omitempty" binding:"max=5"`
+	Sort     string `json:"sort" binding:"omitempty,oneof=recent popular"`
+	Link     string `json:"link" binding:"omitempty,http_url"`
+	Count    int    `binding:"min=1"`
+	Internal string `json:"-" binding:"required"`
+	Delay    int    `json:"delay" binding:"max=60"`
+	Tags     []int  `json:"tags" binding:"omitempty,min=2,max=3"`
+	Name     string `json:"name" binding:"omitempty,min=2"`
+	Contact  string `json:"contact" binding:"omitempty,email"`
+}
+
+func TestGenerateFieldError(t *testing.T) {
+	valid := fieldErrorInput{EventID: "4f1c6b8e-2d1a-4c5e-9b7f-0a3d2e1f5c6b", Count: 1, Internal: "x"}
+
+	tests := []struct {
+		name   string
+		modify func(input *fieldErrorInput)
+		want   FieldError
+	}{
+		{"required", func(input *fieldErrorInput) { input.EventID = "" }, FieldError{
+			Field: "event_id", Rule: "required", Message: "event_id is required",
+		}},
+		{"uuid", func(input *fieldErrorInput) { input.EventID = "abc" }, FieldError{
+			Field: "event_id", Rule: "uuid", Message: "event_id must be a valid uuid",
+		}},
+		{"max with json options", func(input *fieldErrorInput) { input.Title = "too long" }, FieldError{
+			Field: "title", Rule: "max", Param: "5", Message: "title must be at most 5 characters",
+		}},
+		{"oneof", func(input *fieldErrorInput) { input.Sort = "oldest" }, FieldError{
+			Field: "sort", Rule: "oneof", Param: "recent popular", Message: "sort must be one of recent popular",
+		}},
+		{"http_url", func(input *fieldErrorInput) { input.Link = "javascript:alert(1)" }, FieldError{
+			Field: "link", Rule: "http_url", Message: "link must be an http or https link",
+		}},
+		{"min of a number without a json key", func(input *fieldErrorInput) { input.Count = 0 }, FieldError{
+			Field: "Count", Rule: "min", Param: "1", Message: "Count must be at least 1",
+		}},
+		{"max of a number", func(input *fieldErrorInput) { input.Delay = 61 }, FieldError{
+			Field: "delay", Rule: "max", Param: "60", Message: "delay must be at most 60",
+		}},
+		{"min of a list", func(input *fieldErrorInput) { input.Tags = []int{1} }, FieldError{
+			Field: "tags", Rule: "min", Param: "2", Message: "tags must be at least 2 items",
+		}},
+		{"max of a list", func(input *fieldErrorInput) { input.Tags = []int{1, 2, 3, 4} }, FieldError{
+			Field: "tags", Rule: "max", Param: "3", Message: "tags must be at most 3 items",
+		}},
+		{"min of a text", func(input *fieldErrorInput) { input.Name = "a" }, FieldError{
+			Field: "name", Rule: "min", Param: "2", Message: "name must be at least 2 characters",
+		}},
+		{"other rule", func(input *fieldErrorInput) { input.Contact = "nobody" }, FieldError{
+			Field: "contact", Rule: "email", Message: "contact failed the email rule",
+		}},
+		{"ignored json key", func(input *fieldErrorInput) { input.Internal = "" }, FieldError{
+			Field: "Internal", Rule: "required", Message: "Internal is required",
+		}},
+	}
+
+	validate := validator.New()
+	validate.SetTagName("binding")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := valid
+			tt.modify(&input)
+
+			var validationErrs validator.ValidationErrors
+			if err := validate.Struct(&input); !errors.As(err, &validationErrs) {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			if len(validationErrs) != 1 {
+				t.Fatalf("got %d validation errors, want 1", len(validationErrs))
+			}
+
+			// a pointer to the payload is named the same as the payload
+			for _, obj := range []any{input, &input} {
+				if got := generateFieldError(obj, validationErrs[0]); got != tt.want {
+					t.Errorf("generateFieldError(%T) = %+v, want %+v", obj, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+type uuidFieldInput struct {
+	QuestionID uuid.UUID   `json:"question_id" binding:"required"`
+	CategoryID *uuid.UUID  `json:"category_id"`
+	OptionIDs  []uuid.UUID `json:"option_ids"`
+	Content    string      `json:"content"`
+}
+
+func TestUuidFieldErrors(t *testing.T) {
+	const validId = `"4f1c6b8e-2d1a-4c5e-9b7f-0a3d2e1f5c6b"`
+
+	tests := []struct {
+		name    string
+		payload string
+		fields  []string
+	}{
+		{"valid", `{"question_id": ` + validId + `, "category_id": null, "option_ids": [` + validId + `]}`, nil},
+		{"bad uuid", `{"question_id": "abc"}`, []string{"question_id"}},
+		{"bad pointer to a uuid", `{"question_id": ` + validId + `, "category_id": "abc"}`, []string{"category_id"}},
+		{"bad uuid in a list", `{"question_id": ` + validId + `, "option_ids": [` + validId + `, "abc"]}`, []string{"option_ids"}},
+		{"every bad uuid", `{"question_id": "", "option_ids": ["abc"]}`, []string{"question_id", "option_ids"}},
+		{"other fields aren't checked", `{"question_id": ` + validId + `, "content": "abc"}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := uuidFieldInput{}
+			if err := json.Unmarshal([]byte(tt.payload), &input); (err != nil) != (len(tt.fields) > 0) {
+				t.Fatalf("unmarshal error = %v, want an error %v", err, len(tt.fields) > 0)
+			}
+
+			fields := uuidFieldErrors(&input, []byte(tt.payload))
+			if len(fields) != len(tt.fields) {
+				t.Fatalf("got %d fields, want %d: %+v", len(fields), len(tt.fields), fields)
+			}
+			for i, field := range fields {
+				want := FieldError{Field: tt.fields[i], Rule: "uuid", Message: tt.fields[i] + " must be a valid uuid"}
+				if field != want {
+					t.Errorf("field %d = %+v, want %+v", i, field, want)
+				}
+			}
+		})
+	}
+}
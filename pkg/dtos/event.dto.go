@@ -15,6 +15,10 @@ type EventResponse struct {
 	Moderation        bool                  `json:"moderation"`
 	MaxQuestions      models.MaxQuestions   `json:"max_questions,omitempty"`
 	MaxQuestionLength models.QuestionLength `json:"max_question_length,omitempty"`
+	SlowMode          int                   `json:"slow_mode"`
+	FilterAction      models.FilterAction   `json:"filter_action,omitempty"`
+	LockEdits         bool                  `json:"lock_edits"`
+	RequireCategory   bool                  `json:"require_category"`
 	EventCode         string                `json:"event_code,omitempty"`
 	StartDate         *time.Time            `json:"start_date,omitempty"`
 	CreatedAt         *time.Time            `json:"created_at,omitempty"`
@@ -47,6 +51,65 @@ type UpdateMaxQuestions struct {
 	MaxQuestions int `json:"max_questions" binding:"required"`
 }
 
+// slow mode is the seconds a participant has to wait between two questions, 0 turns it off
+type UpdateSlowModeInput struct {
+	SlowMode *int `json:"slow_mode" binding:"required,min=0,max=3600"`
+}
+
+// what happens to a question with banned words
+type UpdateFilterActionInput struct {
+	FilterAction models.FilterAction `json:"filter_action" binding:"required,oneof=reject mask moderate"`
+}
+
+// when edits are locked the author can't edit a question once it has likes or is approved
+type UpdateLockEditsInput struct {
+	LockEdits *bool `json:"lock_edits" binding:"required"`
+}
+
+// when a category is required every new question has to be tagged with one of the event's categories
+type UpdateRequireCategoryInput struct {
+	RequireCategory *bool `json:"require_category" binding:"required"`
+}
+
+// a client joins an event room either by the event id or the event code
+// a reconnecting client sends the last sequence number it saw to get the missed messages
+type JoinRoomInput struct {
+	EventID   string  `json:"event_id" binding:"omitempty,uuid"`
+	EventCode string  `json:"event_code"`
+	LastSeq   *uint64 `json:"last_seq"`
+}
+
+// the live audience of an event room
+// every instance of the app counts its own sessions and the counts of every instance are added up
+// when the counts of the other instances can't be read the response only covers the instance that answered, the scope tells which
+type PresenceResponse struct {
+	EventID uuid.UUID               `json:"event_id"`
+	Scope   PresenceScope           `json:"scope"`
+	Current int                     `json:"current"`
+	Peak    int                     `json:"peak"`
+	PeakAt  *time.Time              `json:"peak_at,omitempty"`
+	Joins   int                     `json:"joins"`
+	Leaves  int                     `json:"leaves"`
+	Trend   []PresenceTrendResponse `json:"trend"`
+}
+
+// which sessions the presence counts
+type PresenceScope string
+
+const (
+	// every session of the event on every instance
+	AllPresence PresenceScope = "all"
+	// only the sessions of the instance that answered, the counts of the others couldn't be read
+	InstancePresence PresenceScope = "instance"
+)
+
+// joins and leaves of an event room within one minute
+type PresenceTrendResponse struct {
+	Minute time.Time `json:"minute"`
+	Joins  int       `json:"joins"`
+	Leaves int       `json:"leaves"`
+}
+
 func GenerateEventResponse(event *models.Event) *EventResponse {
 	if event == nil {
 		return nil
@@ -59,6 +122,10 @@ func GenerateEventResponse(event *models.Event) *EventResponse {
 		Moderation:        event.Moderation,
 		MaxQuestions:      event.MaxQuestions,
 		MaxQuestionLength: event.MaxQuestionLength,
+		SlowMode:          event.SlowMode,
+		FilterAction:      event.FilterAction,
+		LockEdits:         event.LockEdits,
+		RequireCategory:   event.RequireCategory,
 		EventCode:         event.EventCode,
 		StartDate:         CheckNil(event.StartDate),
 		CreatedAt:         CheckNil(event.CreatedAt),
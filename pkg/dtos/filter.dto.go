@@ -0,0 +1,64 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/google/uuid"
+)
+
+type BannedWordResponse struct {
+	WordID    *uuid.UUID `json:"word_id,omitempty"`
+	EventID   *uuid.UUID `json:"event_id,omitempty"`
+	Pattern   string     `json:"pattern,omitempty"`
+	Regex     bool       `json:"regex"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type FilterHitResponse struct {
+	HitID      *uuid.UUID          `json:"hit_id,omitempty"`
+	EventID    *uuid.UUID          `json:"event_id,omitempty"`
+	UserID     *uuid.UUID          `json:"user_id,omitempty"`
+	QuestionID *uuid.UUID          `json:"question_id,omitempty"`
+	Content    string              `json:"content,omitempty"`
+	Matched    string              `json:"matched,omitempty"`
+	Action     models.FilterAction `json:"action,omitempty"`
+	CreatedAt  *time.Time          `json:"created_at,omitempty"`
+}
+
+// a banned word is a word or a few words, or a regular expression when regex is true
+type AddBannedWordInput struct {
+	Pattern string `json:"pattern" binding:"required,max=200"`
+	Regex   bool   `json:"regex"`
+}
+
+func GenerateBannedWordResponse(word *models.BannedWord) *BannedWordResponse {
+	if word == nil {
+		return nil
+	}
+
+	return &BannedWordResponse{
+		WordID:    CheckNil(word.WordID),
+		EventID:   word.EventID,
+		Pattern:   word.Pattern,
+		Regex:     word.Regex,
+		CreatedAt: CheckNil(word.CreatedAt),
+	}
+}
+
+func GenerateFilterHitResponse(hit *models.FilterHit) *FilterHitResponse {
+	if hit == nil {
+		return nil
+	}
+
+	return &FilterHitResponse{
+		HitID:      CheckNil(hit.HitID),
+		EventID:    CheckNil(hit.EventID),
+		UserID:     CheckNil(hit.UserID),
+		QuestionID: hit.QuestionID,
+		Content:    hit.Content,
+		Matched:    hit.Matched,
+		Action:     hit.Action,
+		CreatedAt:  CheckNil(hit.CreatedAt),
+	}
+}
@@ -10,13 +10,20 @@ type LikeResponse struct {
 	QuestionID *uuid.UUID `json:"question_id,omitempty"`
 	UserID     *uuid.UUID `json:"user_id,omitempty"`
 	Liked      bool       `json:"liked"`
+	LikesCount int64      `json:"likes_count"`
+}
+
+// the like count of a question, sent in batches to the event room
+type LikeCountResponse struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	LikesCount int64     `json:"likes_count"`
 }
 
 type ToggleLikeInput struct {
 	QuestionID uuid.UUID `json:"question_id" binding:"required"`
 }
 
-func GenerateLikeResponse(like *models.Like, liked bool) *LikeResponse {
+func GenerateLikeResponse(like *models.Like, liked bool, likesCount int64) *LikeResponse {
 	if like == nil {
 		return nil
 	}
@@ -26,5 +33,6 @@ func GenerateLikeResponse(like *models.Like, liked bool) *LikeResponse {
 		QuestionID: CheckNil(like.QuestionID),
 		UserID:     CheckNil(like.UserID),
 		Liked:      liked,
+		LikesCount: likesCount,
 	}
 }
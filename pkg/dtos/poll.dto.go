@@ -0,0 +1,136 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/google/uuid"
+)
+
+type PollResponse struct {
+	PollID    *uuid.UUID           `json:"poll_id,omitempty"`
+	EventID   *uuid.UUID           `json:"event_id,omitempty"`
+	Question  string               `json:"question,omitempty"`
+	Type      models.PollType      `json:"type,omitempty"`
+	Status    models.PollStatus    `json:"status,omitempty"`
+	RatingMax int                  `json:"rating_max,omitempty"`
+	Options   []PollOptionResponse `json:"options,omitempty"`
+	Results   *PollResultsResponse `json:"results,omitempty"`
+	Voted     bool                 `json:"voted"`
+	OpenedAt  *time.Time           `json:"opened_at,omitempty"`
+	ClosedAt  *time.Time           `json:"closed_at,omitempty"`
+	CreatedAt *time.Time           `json:"created_at,omitempty"`
+	UpdatedAt *time.Time           `json:"updated_at,omitempty"`
+}
+
+type PollOptionResponse struct {
+	OptionID uuid.UUID `json:"option_id"`
+	Content  string    `json:"content"`
+	Position int       `json:"position"`
+}
+
+// the results of a poll, the options are counted for choice polls and the ratings for rating polls
+type PollResultsResponse struct {
+	PollID     uuid.UUID          `json:"poll_id"`
+	EventID    uuid.UUID          `json:"event_id"`
+	Type       models.PollType    `json:"type"`
+	TotalVotes int64              `json:"total_votes"`
+	Options    []PollOptionResult `json:"options,omitempty"`
+	Ratings    []PollRatingResult `json:"ratings,omitempty"`
+	Average    *float64           `json:"average,omitempty"`
+	Terms      []PollTermResult   `json:"terms,omitempty"`
+}
+
+type PollOptionResult struct {
+	OptionID uuid.UUID `json:"option_id"`
+	Content  string    `json:"content"`
+	Votes    int64     `json:"votes"`
+}
+
+type PollRatingResult struct {
+	Rating int   `json:"rating"`
+	Votes  int64 `json:"votes"`
+}
+
+// how often a term was answered in a text poll, for the word cloud
+type PollTermResult struct {
+	Term  string `json:"term"`
+	Count int64  `json:"count"`
+}
+
+type PollAnswerResponse struct {
+	VoteID    *uuid.UUID `json:"vote_id,omitempty"`
+	PollID    *uuid.UUID `json:"poll_id,omitempty"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	Content   string     `json:"content,omitempty"`
+	Term      string     `json:"term,omitempty"`
+	Approved  bool       `json:"approved"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// a choice poll needs its options, a rating poll is rated from 1 to rating_max
+// a text poll is answered with a word or a short phrase
+type CreatePollInput struct {
+	Question  string          `json:"question" binding:"required,max=500"`
+	Type      models.PollType `json:"type" binding:"required,oneof=single multiple rating text"`
+	Options   []string        `json:"options" binding:"max=20,dive,required,max=200"`
+	RatingMax int             `json:"rating_max" binding:"omitempty,min=2,max=10"`
+}
+
+// a single choice vote has one option, a multiple choice vote one or more, a rating vote only the rating
+// and a text vote only the text
+type VotePollInput struct {
+	PollID    uuid.UUID   `json:"poll_id" binding:"required"`
+	OptionIDs []uuid.UUID `json:"option_ids" binding:"max=20"`
+	Rating    *int        `json:"rating" binding:"omitempty,min=1"`
+	Text      string      `json:"text" binding:"max=100"`
+}
+
+type ModeratePollAnswerInput struct {
+	VoteID uuid.UUID `json:"vote_id" binding:"required"`
+}
+
+func GeneratePollResponse(poll *models.Poll) *PollResponse {
+	if poll == nil {
+		return nil
+	}
+
+	options := make([]PollOptionResponse, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		options = append(options, PollOptionResponse{
+			OptionID: option.OptionID,
+			Content:  option.Content,
+			Position: option.Position,
+		})
+	}
+
+	return &PollResponse{
+		PollID:    CheckNil(poll.PollID),
+		EventID:   CheckNil(poll.EventID),
+		Question:  poll.Question,
+		Type:      poll.Type,
+		Status:    poll.Status,
+		RatingMax: poll.RatingMax,
+		Options:   options,
+		OpenedAt:  poll.OpenedAt,
+		ClosedAt:  poll.ClosedAt,
+		CreatedAt: CheckNil(poll.CreatedAt),
+		UpdatedAt: CheckNil(poll.UpdatedAt),
+	}
+}
+
+func GeneratePollAnswerResponse(vote *models.PollVote) *PollAnswerResponse {
+	if vote == nil {
+		return nil
+	}
+
+	return &PollAnswerResponse{
+		VoteID:    CheckNil(vote.VoteID),
+		PollID:    CheckNil(vote.PollID),
+		UserID:    CheckNil(vote.UserID),
+		Content:   vote.Content,
+		Term:      vote.Term,
+		Approved:  vote.Approved,
+		CreatedAt: CheckNil(vote.CreatedAt),
+	}
+}
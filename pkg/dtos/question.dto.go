@@ -1,6 +1,8 @@
 package dtos
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"time"
 
 	"github.com/HudYuSa/mydeen/db/models"
@@ -8,25 +10,91 @@ import (
 )
 
 type QuestionResponse struct {
-	QuestionID *uuid.UUID    `json:"question_id,omitempty"`
-	EventID    *uuid.UUID    `json:"event_id,omitempty"`
-	UserID     *uuid.UUID    `json:"user_id,omitempty"`
-	Username   string        `json:"username,omitempty"`
-	Content    string        `json:"content,omitempty"`
-	Starred    bool          `json:"starred,omitempty"`
-	Approved   bool          `json:"approved,omitempty"`
-	Answered   bool          `json:"answered,omitempty"`
-	LikesCount int           `json:"likes_count"`
-	UserLiked  bool          `json:"user_liked"`
-	CreatedAt  *time.Time    `json:"created_at,omitempty"`
-	UpdatedAt  *time.Time    `json:"updated_at,omitempty"`
-	Event      EventResponse `json:"event,omitempty"`
+	QuestionID *uuid.UUID       `json:"question_id,omitempty"`
+	EventID    *uuid.UUID       `json:"event_id,omitempty"`
+	UserID     *uuid.UUID       `json:"user_id,omitempty"`
+	Username   string           `json:"username,omitempty"`
+	Content    string           `json:"content,omitempty"`
+	Starred    bool             `json:"starred,omitempty"`
+	Approved   bool             `json:"approved,omitempty"`
+	Answered   bool             `json:"answered,omitempty"`
+	LikesCount int              `json:"likes_count"`
+	CategoryID *uuid.UUID       `json:"category_id,omitempty"`
+	UserLiked  bool             `json:"user_liked"`
+	CreatedAt  *time.Time       `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time       `json:"updated_at,omitempty"`
+	Event      EventResponse    `json:"event,omitempty"`
+	Answers    []AnswerResponse `json:"answers"`
+}
+
+// how many questions a user can still ask in an event
+type QuestionQuotaResponse struct {
+	EventID            *uuid.UUID            `json:"event_id,omitempty"`
+	MaxQuestions       models.MaxQuestions   `json:"max_questions"`
+	MaxQuestionLength  models.QuestionLength `json:"max_question_length"`
+	TotalQuestions     int64                 `json:"total_questions"`
+	RemainingQuestions int64                 `json:"remaining_questions"`
+}
+
+// a question that looks like another one, similarity goes from 0 to 1
+type SimilarQuestionResponse struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	Content    string    `json:"content"`
+	LikesCount int64     `json:"likes_count"`
+	Similarity float64   `json:"similarity"`
+}
+
+// how the question list is sorted
+type QuestionSort string
+
+const (
+	RecentSort   QuestionSort = "recent"
+	PopularSort  QuestionSort = "popular"
+	AnsweredSort QuestionSort = "answered"
+)
+
+// which questions the question list shows
+type QuestionFilter string
+
+const (
+	AllFilter      QuestionFilter = ""
+	StarredFilter  QuestionFilter = "starred"
+	AnsweredFilter QuestionFilter = "answered"
+	PendingFilter  QuestionFilter = "pending"
+)
+
+// QuestionCursor is the position after the last question of a page
+// it holds every key the list can be sorted by so the next page starts right after it
+type QuestionCursor struct {
+	Sort       QuestionSort `json:"sort"`
+	LikesCount int64        `json:"likes_count"`
+	Answered   bool         `json:"answered"`
+	CreatedAt  time.Time    `json:"created_at"`
+	QuestionID uuid.UUID    `json:"question_id"`
+}
+
+// Encode turns the cursor into the opaque string the client sends back
+func (c QuestionCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString(EncodeJson(c))
+}
+
+func DecodeQuestionCursor(cursor string) (QuestionCursor, error) {
+	var c QuestionCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(data, &c)
+	return c, err
 }
 
 type CreateQuestionInput struct {
-	EventID  string `json:"event_id" binding:"required"`
-	Content  string `json:"content" binding:"required"`
-	Username string `json:"username"`
+	EventID    string     `json:"event_id" binding:"required,uuid"`
+	Content    string     `json:"content" binding:"required"`
+	Username   string     `json:"username"`
+	CategoryID *uuid.UUID `json:"category_id"`
 }
 
 type DeleteQuestionInput struct {
@@ -34,10 +102,35 @@ type DeleteQuestionInput struct {
 }
 
 type EditQuestionInput struct {
-	QuestionID string `json:"question_id" binding:"required"`
+	QuestionID string `json:"question_id" binding:"required,uuid"`
 	Content    string `json:"content" binding:"required"`
 }
 
+type ModerateQuestionInput struct {
+	QuestionID uuid.UUID `json:"question_id" binding:"required"`
+}
+
+type AdminEditQuestionInput struct {
+	QuestionID uuid.UUID `json:"question_id" binding:"required"`
+	Content    string    `json:"content" binding:"required"`
+}
+
+// the question the duplicates are merged into, the duplicates are deleted and their likes go to the question
+type MergeQuestionsInput struct {
+	QuestionID   uuid.UUID   `json:"question_id" binding:"required"`
+	DuplicateIDs []uuid.UUID `json:"duplicate_ids" binding:"required,min=1,max=50"`
+}
+
+type StarQuestionInput struct {
+	QuestionID uuid.UUID `json:"question_id" binding:"required"`
+	Starred    bool      `json:"starred"`
+}
+
+type AnswerQuestionInput struct {
+	QuestionID uuid.UUID `json:"question_id" binding:"required"`
+	Answered   bool      `json:"answered"`
+}
+
 func GenerateQuestionResponse(question *models.Question, user User) *QuestionResponse {
 	if question == nil {
 		return nil
@@ -52,6 +145,15 @@ func GenerateQuestionResponse(question *models.Question, user User) *QuestionRes
 		}
 	}
 
+	return GenerateQuestionResponseWithLikes(question, int64(len(question.Likes)), userLiked)
+}
+
+// GenerateQuestionResponseWithLikes is for questions whose likes were counted by the database instead of loaded
+func GenerateQuestionResponseWithLikes(question *models.Question, likesCount int64, userLiked bool) *QuestionResponse {
+	if question == nil {
+		return nil
+	}
+
 	return &QuestionResponse{
 		QuestionID: CheckNil(question.QuestionID),
 		EventID:    CheckNil(question.EventID),
@@ -61,10 +163,12 @@ func GenerateQuestionResponse(question *models.Question, user User) *QuestionRes
 		Starred:    question.Starred,
 		Approved:   question.Approved,
 		Answered:   question.Answered,
-		LikesCount: len(question.Likes),
+		LikesCount: int(likesCount),
+		CategoryID: question.CategoryID,
 		UserLiked:  userLiked,
 		CreatedAt:  CheckNil(question.CreatedAt),
 		UpdatedAt:  CheckNil(question.UpdatedAt),
 		Event:      *GenerateEventResponse(&question.Event),
+		Answers:    []AnswerResponse{},
 	}
 }
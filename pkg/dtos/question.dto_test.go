@@ -0,0 +1,59 @@
+package dtos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestQuestionCursor(t *testing.T) {
+	createdAt := time.Date(2023, 10, 5, 14, 30, 15, 123456789, time.UTC)
+	questionId := uuid.MustParse("4f1c6b8e-2d1a-4c5e-9b7f-0a3d2e1f5c6b")
+
+	tests := []struct {
+		name   string
+		cursor QuestionCursor
+	}{
+		{"zero", QuestionCursor{}},
+		{"recent", QuestionCursor{Sort: RecentSort, CreatedAt: createdAt, QuestionID: questionId}},
+		{"popular", QuestionCursor{Sort: PopularSort, LikesCount: 42, CreatedAt: createdAt, QuestionID: questionId}},
+		{"answered", QuestionCursor{Sort: AnsweredSort, Answered: true, CreatedAt: createdAt, QuestionID: questionId}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeQuestionCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decoded.Sort != tt.cursor.Sort || decoded.LikesCount != tt.cursor.LikesCount || decoded.Answered != tt.cursor.Answered || decoded.QuestionID != tt.cursor.QuestionID {
+				t.Errorf("decoded = %+v, want %+v", decoded, tt.cursor)
+			}
+			// the cursor keeps the time to the nanosecond so no question is skipped between pages
+			if !decoded.CreatedAt.Equal(tt.cursor.CreatedAt) {
+				t.Errorf("created at = %v, want %v", decoded.CreatedAt, tt.cursor.CreatedAt)
+			}
+		})
+	}
+}
+
+func TestDecodeQuestionCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"padded base64", "e30="},
+		{"not json", "bm90IGpzb24"},
+		{"wrong types", "eyJsaWtlc19jb3VudCI6ImEifQ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeQuestionCursor(tt.cursor); err == nil {
+				t.Errorf("expected an error for %q", tt.cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/google/uuid"
+)
+
+type QuizResponse struct {
+	QuizID       *uuid.UUID               `json:"quiz_id,omitempty"`
+	EventID      *uuid.UUID               `json:"event_id,omitempty"`
+	Title        string                   `json:"title,omitempty"`
+	Status       models.QuizStatus        `json:"status,omitempty"`
+	CurrentRound int                      `json:"current_round"`
+	TotalRounds  int                      `json:"total_rounds"`
+	Rounds       []QuizRoundResponse      `json:"rounds,omitempty"`
+	Results      *QuizLeaderboardResponse `json:"results,omitempty"`
+	StartedAt    *time.Time               `json:"started_at,omitempty"`
+	FinishedAt   *time.Time               `json:"finished_at,omitempty"`
+	CreatedAt    *time.Time               `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time               `json:"updated_at,omitempty"`
+}
+
+// a round as the players see it, the correct options are only there once the round ended
+type QuizRoundResponse struct {
+	RoundID    *uuid.UUID           `json:"round_id,omitempty"`
+	QuizID     *uuid.UUID           `json:"quiz_id,omitempty"`
+	Position   int                  `json:"position"`
+	Question   string               `json:"question,omitempty"`
+	TimeLimit  int                  `json:"time_limit,omitempty"`
+	Points     int                  `json:"points,omitempty"`
+	Options    []QuizOptionResponse `json:"options,omitempty"`
+	OpenedAt   *time.Time           `json:"opened_at,omitempty"`
+	ClosesAt   *time.Time           `json:"closes_at,omitempty"`
+	EndedAt    *time.Time           `json:"ended_at,omitempty"`
+	ServerTime *time.Time           `json:"server_time,omitempty"`
+}
+
+type QuizOptionResponse struct {
+	OptionID uuid.UUID `json:"option_id"`
+	Content  string    `json:"content"`
+	Correct  *bool     `json:"correct,omitempty"`
+	Answers  *int64    `json:"answers,omitempty"`
+}
+
+type QuizLeaderboardResponse struct {
+	QuizID       uuid.UUID          `json:"quiz_id"`
+	EventID      uuid.UUID          `json:"event_id"`
+	Round        int                `json:"round"`
+	TotalPlayers int64              `json:"total_players"`
+	Players      []QuizPlayerResult `json:"players"`
+}
+
+type QuizPlayerResult struct {
+	Rank           int       `json:"rank"`
+	UserID         uuid.UUID `json:"user_id"`
+	DisplayName    string    `json:"display_name"`
+	Score          int       `json:"score"`
+	CorrectAnswers int       `json:"correct_answers"`
+}
+
+type CreateQuizInput struct {
+	Title  string                 `json:"title" binding:"required,max=200"`
+	Rounds []CreateQuizRoundInput `json:"rounds" binding:"required,min=1,max=50,dive"`
+}
+
+// a round is answered with one option, at least one of them is correct
+type CreateQuizRoundInput struct {
+	Question  string                  `json:"question" binding:"required,max=500"`
+	TimeLimit int                     `json:"time_limit" binding:"omitempty,min=5,max=300"`
+	Points    int                     `json:"points" binding:"omitempty,min=1,max=10000"`
+	Options   []CreateQuizOptionInput `json:"options" binding:"required,min=2,max=10,dive"`
+}
+
+type CreateQuizOptionInput struct {
+	Content string `json:"content" binding:"required,max=200"`
+	Correct bool   `json:"correct"`
+}
+
+type JoinQuizInput struct {
+	QuizID      uuid.UUID `json:"quiz_id" binding:"required"`
+	DisplayName string    `json:"display_name" binding:"required,max=50"`
+}
+
+type AnswerQuizInput struct {
+	RoundID  uuid.UUID `json:"round_id" binding:"required"`
+	OptionID uuid.UUID `json:"option_id" binding:"required"`
+}
+
+func GenerateQuizResponse(quiz *models.Quiz) *QuizResponse {
+	if quiz == nil {
+		return nil
+	}
+
+	return &QuizResponse{
+		QuizID:       CheckNil(quiz.QuizID),
+		EventID:      CheckNil(quiz.EventID),
+		Title:        quiz.Title,
+		Status:       quiz.Status,
+		CurrentRound: quiz.CurrentRound,
+		TotalRounds:  len(quiz.Rounds),
+		StartedAt:    quiz.StartedAt,
+		FinishedAt:   quiz.FinishedAt,
+		CreatedAt:    CheckNil(quiz.CreatedAt),
+		UpdatedAt:    CheckNil(quiz.UpdatedAt),
+	}
+}
+
+// GenerateQuizRoundResponse gives the round with its options
+// the correct options are only marked when withCorrect is true
+func GenerateQuizRoundResponse(round *models.QuizRound, withCorrect bool) *QuizRoundResponse {
+	if round == nil {
+		return nil
+	}
+
+	options := make([]QuizOptionResponse, 0, len(round.Options))
+	for _, option := range round.Options {
+		optionResponse := QuizOptionResponse{
+			OptionID: option.OptionID,
+			Content:  option.Content,
+		}
+		if withCorrect {
+			correct := option.Correct
+			optionResponse.Correct = &correct
+		}
+		options = append(options, optionResponse)
+	}
+
+	return &QuizRoundResponse{
+		RoundID:   CheckNil(round.RoundID),
+		QuizID:    CheckNil(round.QuizID),
+		Position:  round.Position,
+		Question:  round.Question,
+		TimeLimit: round.TimeLimit,
+		Points:    round.Points,
+		Options:   options,
+		OpenedAt:  round.OpenedAt,
+		ClosesAt:  round.ClosesAt,
+		EndedAt:   round.EndedAt,
+	}
+}
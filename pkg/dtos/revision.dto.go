@@ -0,0 +1,44 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/google/uuid"
+)
+
+type QuestionRevisionResponse struct {
+	RevisionID      *uuid.UUID    `json:"revision_id,omitempty"`
+	QuestionID      *uuid.UUID    `json:"question_id,omitempty"`
+	PreviousContent string        `json:"previous_content"`
+	Content         string        `json:"content"`
+	Editor          models.Editor `json:"editor"`
+	EditorID        *uuid.UUID    `json:"editor_id,omitempty"`
+	CreatedAt       *time.Time    `json:"created_at,omitempty"`
+	MergedFrom      *uuid.UUID    `json:"merged_from,omitempty"`
+}
+
+// QuestionRevisionsResponse is the edit history of a question
+// the revisions of the duplicates merged into it are kept apart, they aren't edits of the question
+type QuestionRevisionsResponse struct {
+	QuestionID      *uuid.UUID                 `json:"question_id,omitempty"`
+	Revisions       []QuestionRevisionResponse `json:"revisions"`
+	MergedRevisions []QuestionRevisionResponse `json:"merged_revisions"`
+}
+
+func GenerateQuestionRevisionResponse(revision *models.QuestionRevision) *QuestionRevisionResponse {
+	if revision == nil {
+		return nil
+	}
+
+	return &QuestionRevisionResponse{
+		RevisionID:      CheckNil(revision.RevisionID),
+		QuestionID:      CheckNil(revision.QuestionID),
+		PreviousContent: revision.PreviousContent,
+		Content:         revision.Content,
+		Editor:          revision.Editor,
+		EditorID:        CheckNil(revision.EditorID),
+		CreatedAt:       CheckNil(revision.CreatedAt),
+		MergedFrom:      revision.MergedFrom,
+	}
+}
@@ -1,47 +1,63 @@
 package middlewares
 
 import (
-	"reflect"
-
 	"github.com/HudYuSa/mydeen/db/models"
-	"github.com/HudYuSa/mydeen/internal/config"
-	"github.com/HudYuSa/mydeen/internal/connection"
 	"github.com/HudYuSa/mydeen/pkg/dtos"
-	"github.com/HudYuSa/mydeen/pkg/utils"
 	"github.com/olahol/melody"
 )
 
-// this function will authenticate admin and response with false if there's an error and true if there isn't
-func WSAuthenticateAdmin(s *melody.Session, group dtos.WebSocketGroup) bool {
-	accessToken := utils.GetTokenWS(s, "access_token", "Authorization")
+// the account of a websocket connection is resolved once by IdentifyAccount when it's upgraded
+// and kept in the session keys, so every message doesn't have to validate the token again
 
-	// if there's no token from header or cookie
-	if reflect.ValueOf(accessToken).IsZero() {
-		s.Write(dtos.WebSocketRespondError(group, "You're not allowed to acces this endpoint"))
-		return false
+// WSSetAccount moves the role and the admin or master of the upgrade request onto the session
+func WSSetAccount(s *melody.Session) dtos.Role {
+	requestCtx := s.Request.Context()
+
+	role, ok := requestCtx.Value("role").(dtos.Role)
+	if !ok {
+		role = dtos.ParticipantRole
 	}
+	s.Set("role", role)
 
-	// validate the token and get the user from the sub/subject
-	account, err := utils.ValidateToken(accessToken, config.GlobalConfig.AccessTokenPublicKey)
-	if err != nil {
-		s.Write(dtos.WebSocketRespondError(group, err.Error()))
-		return false
+	if admin, ok := requestCtx.Value("currentAdmin").(models.Admin); ok {
+		s.Set("currentAdmin", admin)
+	}
+	if master, ok := requestCtx.Value("currentMaster").(models.Master); ok {
+		s.Set("currentMaster", master)
 	}
 
-	adminId, adminOk := account["admin_id"]
-	if adminOk {
-		var admin models.Admin
-		adminResult := connection.DB.First(&admin, "admin_id = ?", adminId)
-		if adminResult.Error != nil {
-			s.Write(dtos.WebSocketRespondError(group, "you're not allowed to access this endpoint"))
-			return false
-		}
+	return role
+}
 
-		// if there's no error set the admin and then return false
-		s.Set("currentAdmin", admin)
-		return true
-	} else {
-		s.Write(dtos.WebSocketRespondError(group, "you're not allowed to access this endpoing"))
+// WSRole returns the role of the session, sessions without one are participants
+func WSRole(s *melody.Session) dtos.Role {
+	value, exists := s.Get("role")
+	if !exists {
+		return dtos.ParticipantRole
+	}
+
+	role, ok := value.(dtos.Role)
+	if !ok {
+		return dtos.ParticipantRole
+	}
+
+	return role
+}
+
+// this function will authenticate admin and response with false if there's an error and true if there isn't
+func WSAuthenticateAdmin(s *melody.Session, group dtos.WebSocketGroup) bool {
+	switch WSRole(s) {
+	case dtos.AdminRole:
+		if _, exists := s.Get("currentAdmin"); exists {
+			return true
+		}
+		dtos.WebSocketWriteError(s, group, dtos.UnauthorizedCode, "you're not allowed to access this endpoint")
+		return false
+	case dtos.ParticipantRole:
+		dtos.WebSocketWriteError(s, group, dtos.UnauthorizedCode, "You're not allowed to acces this endpoint")
+		return false
+	default:
+		dtos.WebSocketWriteError(s, group, dtos.ForbiddenCode, "you're not allowed to access this endpoing")
 		return false
 	}
 }
@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"reflect"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/internal/config"
+	"github.com/HudYuSa/mydeen/internal/connection"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/HudYuSa/mydeen/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// IdentifyAccount sets the role of the request and the admin or master behind its token
+// but lets anonymous users through as participants, for endpoints that show more data to admins
+func IdentifyAccount() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set("role", dtos.ParticipantRole)
+
+		accessToken := utils.GetToken(ctx, "access_token", "Authorization")
+
+		// if there's no token from header or cookie
+		if reflect.ValueOf(accessToken).IsZero() {
+			ctx.Next()
+			return
+		}
+
+		account, err := utils.ValidateToken(accessToken, config.GlobalConfig.AccessTokenPublicKey)
+		if err != nil {
+			ctx.Next()
+			return
+		}
+
+		if adminId, adminOk := account["admin_id"]; adminOk {
+			var admin models.Admin
+			adminResult := connection.DB.First(&admin, "admin_id = ?", adminId)
+			if adminResult.Error == nil {
+				ctx.Set("currentAdmin", admin)
+				ctx.Set("role", dtos.AdminRole)
+			}
+		} else if masterId, masterOk := account["master_id"]; masterOk {
+			var master models.Master
+			masterResult := connection.DB.First(&master, "master_id = ?", masterId)
+			if masterResult.Error == nil {
+				ctx.Set("currentMaster", master)
+				ctx.Set("role", dtos.MasterRole)
+			}
+		}
+
+		ctx.Next()
+	}
+}
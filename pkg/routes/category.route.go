@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/HudYuSa/mydeen/pkg/controllers"
+	"github.com/HudYuSa/mydeen/pkg/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryRoutes interface {
+	SetupRoutes(rg *gin.RouterGroup)
+}
+
+type categoryRoutes struct {
+	CategoryController controllers.CategoryController
+}
+
+func NewCategoryRoutes(categoryController controllers.CategoryController) CategoryRoutes {
+	return &categoryRoutes{
+		CategoryController: categoryController,
+	}
+}
+
+func (cr *categoryRoutes) SetupRoutes(rg *gin.RouterGroup) {
+	router := rg.Group("/categories")
+
+	router.GET("/event/:event_id", cr.CategoryController.GetEventCategories)
+
+	event := router.Group("/event/:event_id", middlewares.AuthenticateAdmin())
+	event.POST("", cr.CategoryController.CreateCategory)
+	event.DELETE("/:category_id", cr.CategoryController.DeleteCategory)
+	event.GET("/counts", cr.CategoryController.GetCategoryCounts)
+}
@@ -39,4 +39,9 @@ func (er *eventRoutes) SetupRoutes(rg *gin.RouterGroup) {
 	router.PATCH("/:event_id/moderation", er.EventController.UpdateModeration)
 	router.PATCH("/:event_id/max-question-length", er.EventController.UpdateMaxQuestionLength)
 	router.PATCH("/:event_id/max-questions", er.EventController.UpdateMaxQuestions)
+	router.PATCH("/:event_id/slow-mode", er.EventController.UpdateSlowMode)
+	router.PATCH("/:event_id/filter-action", er.EventController.UpdateFilterAction)
+	router.PATCH("/:event_id/lock-edits", er.EventController.UpdateLockEdits)
+	router.PATCH("/:event_id/require-category", er.EventController.UpdateRequireCategory)
+	router.GET("/:event_id/presence", er.EventController.GetEventPresence)
 }
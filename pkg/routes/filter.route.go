@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"github.com/HudYuSa/mydeen/pkg/controllers"
+	"github.com/HudYuSa/mydeen/pkg/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+type FilterRoutes interface {
+	SetupRoutes(rg *gin.RouterGroup)
+}
+
+type filterRoutes struct {
+	FilterController controllers.FilterController
+}
+
+func NewFilterRoutes(filterController controllers.FilterController) FilterRoutes {
+	return &filterRoutes{
+		FilterController: filterController,
+	}
+}
+
+func (fr *filterRoutes) SetupRoutes(rg *gin.RouterGroup) {
+	router := rg.Group("/filters")
+
+	// the global list is kept by the master
+	global := router.Group("/global", middlewares.AuthenticateMaster())
+	global.GET("", fr.FilterController.GetGlobalWords)
+	global.POST("", fr.FilterController.AddGlobalWord)
+	global.DELETE("/:word_id", fr.FilterController.DeleteGlobalWord)
+
+	event := router.Group("/event/:event_id", middlewares.AuthenticateAdmin())
+	event.GET("", fr.FilterController.GetEventWords)
+	event.POST("", fr.FilterController.AddEventWord)
+	event.DELETE("/:word_id", fr.FilterController.DeleteEventWord)
+	event.GET("/hits", fr.FilterController.GetEventHits)
+}
@@ -16,6 +16,11 @@ func InitializeRoutes(router *gin.RouterGroup) {
 	event := NewEventRoutes(controllers.Event)
 	question := NewQuestionRoutes(controllers.Question)
 	webSocket := NewWebSocketController(controllers.WebSocket)
+	stream := NewStreamRoutes(controllers.Stream)
+	poll := NewPollRoutes(controllers.Poll)
+	quiz := NewQuizRoutes(controllers.Quiz)
+	filter := NewFilterRoutes(controllers.Filter)
+	category := NewCategoryRoutes(controllers.Category)
 
 	// setup routes
 	master.SetupRoutes(router)
@@ -24,4 +29,9 @@ func InitializeRoutes(router *gin.RouterGroup) {
 	event.SetupRoutes(router)
 	question.SetupRoutes(router)
 	webSocket.SetupRoutes(router)
+	stream.SetupRoutes(router)
+	poll.SetupRoutes(router)
+	quiz.SetupRoutes(router)
+	filter.SetupRoutes(router)
+	category.SetupRoutes(router)
 }
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/HudYuSa/mydeen/pkg/controllers"
+	"github.com/HudYuSa/mydeen/pkg/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+type PollRoutes interface {
+	SetupRoutes(rg *gin.RouterGroup)
+}
+
+type pollRoutes struct {
+	PollController controllers.PollController
+}
+
+func NewPollRoutes(pollController controllers.PollController) PollRoutes {
+	return &pollRoutes{
+		PollController: pollController,
+	}
+}
+
+func (pr *pollRoutes) SetupRoutes(rg *gin.RouterGroup) {
+	router := rg.Group("/polls")
+
+	router.GET("/event/:event_id", middlewares.IdentifyAccount(), pr.PollController.GetEventPolls)
+
+	router.Use(middlewares.AuthenticateAdmin())
+	router.POST("/event/:event_id", pr.PollController.CreatePoll)
+	router.PATCH("/:poll_id/open", pr.PollController.OpenPoll)
+	router.PATCH("/:poll_id/close", pr.PollController.ClosePoll)
+	router.DELETE("/:poll_id", pr.PollController.DeletePoll)
+	router.GET("/:poll_id/results", pr.PollController.GetPollResults)
+	router.GET("/:poll_id/answers", pr.PollController.GetPollAnswers)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/HudYuSa/mydeen/pkg/controllers"
+	"github.com/HudYuSa/mydeen/pkg/middlewares"
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,5 +23,8 @@ func NewQuestionRoutes(questionController controllers.QuestionController) Questi
 func (qr *questionRoutes) SetupRoutes(rg *gin.RouterGroup) {
 	router := rg.Group("/questions")
 
-	router.GET("/:event_id", qr.QuestionController.GetEventQuestions)
+	router.GET("/:event_id", middlewares.IdentifyAccount(), qr.QuestionController.GetEventQuestions)
+	router.GET("/:event_id/total", qr.QuestionController.GetUserTotalQuestions)
+	router.GET("/:event_id/similar", middlewares.IdentifyAccount(), qr.QuestionController.GetSimilarQuestions)
+	router.GET("/:event_id/revisions/:question_id", middlewares.AuthenticateAdmin(), qr.QuestionController.GetQuestionRevisions)
 }
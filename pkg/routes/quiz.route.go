@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/HudYuSa/mydeen/pkg/controllers"
+	"github.com/HudYuSa/mydeen/pkg/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+type QuizRoutes interface {
+	SetupRoutes(rg *gin.RouterGroup)
+}
+
+type quizRoutes struct {
+	QuizController controllers.QuizController
+}
+
+func NewQuizRoutes(quizController controllers.QuizController) QuizRoutes {
+	return &quizRoutes{
+		QuizController: quizController,
+	}
+}
+
+func (qr *quizRoutes) SetupRoutes(rg *gin.RouterGroup) {
+	router := rg.Group("/quizzes")
+
+	router.GET("/event/:event_id", middlewares.IdentifyAccount(), qr.QuizController.GetEventQuizzes)
+
+	router.Use(middlewares.AuthenticateAdmin())
+	router.POST("/event/:event_id", qr.QuizController.CreateQuiz)
+	router.PATCH("/:quiz_id/next", qr.QuizController.NextRound)
+	router.PATCH("/:quiz_id/finish", qr.QuizController.FinishQuiz)
+	router.DELETE("/:quiz_id", qr.QuizController.DeleteQuiz)
+	router.GET("/:quiz_id/results", qr.QuizController.GetQuizResults)
+}
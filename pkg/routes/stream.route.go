@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/HudYuSa/mydeen/pkg/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+type StreamRoutes interface {
+	SetupRoutes(rg *gin.RouterGroup)
+}
+
+type streamRoutes struct {
+	StreamController controllers.StreamController
+}
+
+func NewStreamRoutes(streamController controllers.StreamController) StreamRoutes {
+	return &streamRoutes{
+		StreamController: streamController,
+	}
+}
+
+func (sr *streamRoutes) SetupRoutes(rg *gin.RouterGroup) {
+	router := rg.Group("/stream")
+
+	router.GET("/:event_id", sr.StreamController.StreamEvent)
+}
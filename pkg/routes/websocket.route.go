@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/HudYuSa/mydeen/pkg/controllers"
+	"github.com/HudYuSa/mydeen/pkg/middlewares"
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,5 +23,6 @@ func NewWebSocketController(webSocketController controllers.WebSocketController)
 func (wsr *webSocketRoutes) SetupRoutes(rg *gin.RouterGroup) {
 	router := rg.Group("/ws")
 
-	router.GET("", controllers.WebSocket.UpgradeConnection)
+	// admins and masters are identified once, when the connection is upgraded
+	router.GET("", middlewares.IdentifyAccount(), controllers.WebSocket.UpgradeConnection)
 }
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HudYuSa/mydeen/internal/config"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// the broadcast backend carries room messages to every running instance of the app
+// every instance then writes the message to its own websocket sessions
+// the backend also hands out the sequence number of every message
+// so all instances agree on the order of an event's messages
+// a message too large for a postgres notification is kept in a table and the notification only carries its id
+
+const (
+	BroadcastMemory   = "memory"
+	BroadcastPostgres = "postgres"
+)
+
+const (
+	// the postgres channel all room messages are sent on
+	broadcastChannel = "mydeen_rooms"
+	// postgres refuses notification payloads of 8000 bytes and more
+	maxNotifyPayload = 7900
+	// how long a message too large for a notification is kept for the listeners to read it
+	roomMessageRetention = 5 * time.Minute
+	// how long the listener waits before reconnecting after an error
+	listenRetryInterval = time.Second
+)
+
+// Audience describes which sessions of a room get a message
+// a session gets it when it matches any of the fields
+// it's plain data so it can travel between instances, an empty audience means everyone
+type Audience struct {
+	AdminID *uuid.UUID  `json:"admin_id,omitempty"`
+	UserID  *uuid.UUID  `json:"user_id,omitempty"`
+	Roles   []dtos.Role `json:"roles,omitempty"`
+}
+
+// BroadcastHandler gets every published message together with its room sequence number
+type BroadcastHandler func(eventId uuid.UUID, seq uint64, msg []byte, audience Audience)
+
+type Broadcaster interface {
+	Publish(eventId uuid.UUID, msg []byte, audience Audience) error
+	Seq(eventId uuid.UUID) (uint64, error)
+	Listen(handler BroadcastHandler)
+}
+
+// NewBroadcaster creates the backend chosen in the config, the memory backend is the default
+func NewBroadcaster(backend string, db *gorm.DB) (Broadcaster, error) {
+	switch backend {
+	case "", BroadcastMemory:
+		return NewMemoryBroadcaster(), nil
+	case BroadcastPostgres:
+		return NewPostgresBroadcaster(db), nil
+	default:
+		return nil, fmt.Errorf("unknown broadcast backend %q", backend)
+	}
+}
+
+// memory backend, for a single instance
+
+type memoryBroadcaster struct {
+	mu      sync.Mutex
+	seq     map[uuid.UUID]uint64
+	handler BroadcastHandler
+}
+
+func NewMemoryBroadcaster() Broadcaster {
+	return &memoryBroadcaster{
+		seq: map[uuid.UUID]uint64{},
+	}
+}
+
+func (mb *memoryBroadcaster) Publish(eventId uuid.UUID, msg []byte, audience Audience) error {
+	// the lock is held while handling so the messages are delivered in sequence order
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	mb.seq[eventId]++
+	if mb.handler != nil {
+		mb.handler(eventId, mb.seq[eventId], msg, audience)
+	}
+
+	return nil
+}
+
+func (mb *memoryBroadcaster) Seq(eventId uuid.UUID) (uint64, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	return mb.seq[eventId], nil
+}
+
+func (mb *memoryBroadcaster) Listen(handler BroadcastHandler) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	mb.handler = handler
+}
+
+// postgres backend, messages go through LISTEN/NOTIFY on the app database
+
+type postgresBroadcaster struct {
+	DB *gorm.DB
+}
+
+// the payload of a notification
+// it carries either the message or the id of the message kept in room_messages
+type notification struct {
+	EventID   uuid.UUID       `json:"event_id"`
+	Seq       uint64          `json:"seq"`
+	Audience  Audience        `json:"audience"`
+	Msg       json.RawMessage `json:"msg,omitempty"`
+	MessageID *uuid.UUID      `json:"message_id,omitempty"`
+}
+
+func NewPostgresBroadcaster(db *gorm.DB) Broadcaster {
+	return &postgresBroadcaster{
+		DB: db,
+	}
+}
+
+func (pb *postgresBroadcaster) Publish(eventId uuid.UUID, msg []byte, audience Audience) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	// the sequence row stays locked until commit
+	// so notifications of one event are committed, and delivered, in sequence order
+	return pb.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var seq uint64
+		seqResult := tx.Raw(`INSERT INTO "room_sequences" ("event_id", "seq") VALUES (?, 1)
+			ON CONFLICT ("event_id") DO UPDATE SET "seq" = "room_sequences"."seq" + 1
+			RETURNING "seq"`, eventId).Scan(&seq)
+		if seqResult.Error != nil {
+			return seqResult.Error
+		}
+
+		n := notification{
+			EventID:  eventId,
+			Seq:      seq,
+			Audience: audience,
+			Msg:      msg,
+		}
+
+		payload, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+
+		// the message goes in the table and the listeners read it from there
+		if len(payload) > maxNotifyPayload {
+			var messageId uuid.UUID
+			storeResult := tx.Raw(`INSERT INTO "room_messages" ("event_id", "msg", "created_at") VALUES (?, ?, ?) RETURNING "message_id"`, eventId, string(msg), time.Now().UTC()).Scan(&messageId)
+			if storeResult.Error != nil {
+				return storeResult.Error
+			}
+
+			// the messages every listener had the time to read aren't needed anymore
+			cleanResult := tx.Exec(`DELETE FROM "room_messages" WHERE "created_at" < ?`, time.Now().UTC().Add(-roomMessageRetention))
+			if cleanResult.Error != nil {
+				return cleanResult.Error
+			}
+
+			n.Msg = nil
+			n.MessageID = &messageId
+
+			payload, err = json.Marshal(n)
+			if err != nil {
+				return err
+			}
+
+			if len(payload) > maxNotifyPayload {
+				return errors.New("broadcast audience is too large")
+			}
+		}
+
+		return tx.Exec("SELECT pg_notify(?, ?)", broadcastChannel, string(payload)).Error
+	})
+}
+
+func (pb *postgresBroadcaster) Seq(eventId uuid.UUID) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var seq uint64
+	seqResult := pb.DB.WithContext(ctx).Raw(`SELECT "seq" FROM "room_sequences" WHERE "event_id" = ?`, eventId).Scan(&seq)
+	return seq, seqResult.Error
+}
+
+// Listen starts listening in the background, it reconnects when the connection is lost
+func (pb *postgresBroadcaster) Listen(handler BroadcastHandler) {
+	go func() {
+		for {
+			if err := pb.listen(handler); err != nil {
+				log.Println("broadcast listener: ", err)
+			}
+			time.Sleep(listenRetryInterval)
+		}
+	}()
+}
+
+// listen holds one connection out of the pool to wait for notifications until it fails
+func (pb *postgresBroadcaster) listen(handler BroadcastHandler) error {
+	ctx := context.Background()
+
+	sqlDB, err := pb.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+
+		if _, err := pgxConn.Exec(ctx, "LISTEN "+broadcastChannel); err != nil {
+			return err
+		}
+		// the connection goes back to the pool afterwards so it has to stop listening
+		defer pgxConn.Exec(ctx, "UNLISTEN "+broadcastChannel)
+
+		for {
+			received, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+
+			var payload notification
+			if err := json.Unmarshal([]byte(received.Payload), &payload); err != nil {
+				log.Println("broadcast listener: ", err)
+				continue
+			}
+
+			msg := []byte(payload.Msg)
+			if payload.MessageID != nil {
+				msg, err = pb.storedMessage(*payload.MessageID)
+				if err != nil {
+					log.Println("broadcast listener: ", err, " message: ", *payload.MessageID)
+					continue
+				}
+			}
+
+			handler(payload.EventID, payload.Seq, msg, payload.Audience)
+		}
+	})
+}
+
+// storedMessage reads a message that was too large for its notification
+func (pb *postgresBroadcaster) storedMessage(messageId uuid.UUID) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	var msg string
+	msgResult := pb.DB.WithContext(ctx).Raw(`SELECT "msg" FROM "room_messages" WHERE "message_id" = ?`, messageId).Scan(&msg)
+	if msgResult.Error != nil {
+		return nil, msgResult.Error
+	}
+
+	if msgResult.RowsAffected < 1 {
+		return nil, errors.New("stored broadcast message not found")
+	}
+
+	return []byte(msg), nil
+}
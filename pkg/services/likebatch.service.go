@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/internal/config"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// the like batcher gathers the liked and unliked questions of every event over a short window
+// and then sends one message with the like count of each of them to the event room
+// so a room full of people upvoting at once doesn't get a message for every single like
+// the counts are read from the database, so they are right no matter which instance took the likes
+
+type LikeBatcher interface {
+	// Enabled is false when likes are sent to the room one by one
+	Enabled() bool
+	// Add marks the like count of the question as changed
+	Add(eventId uuid.UUID, questionId uuid.UUID)
+}
+
+type likeBatcher struct {
+	DB       *gorm.DB
+	Room     RoomService
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]map[uuid.UUID]struct{}
+}
+
+// NewLikeBatcher creates a batcher that flushes every interval, an interval of 0 turns batching off
+func NewLikeBatcher(db *gorm.DB, room RoomService, interval time.Duration) LikeBatcher {
+	lb := &likeBatcher{
+		DB:       db,
+		Room:     room,
+		interval: interval,
+		pending:  map[uuid.UUID]map[uuid.UUID]struct{}{},
+	}
+
+	if lb.Enabled() {
+		go lb.run()
+	}
+
+	return lb
+}
+
+func (lb *likeBatcher) Enabled() bool {
+	return lb.interval > 0
+}
+
+func (lb *likeBatcher) Add(eventId uuid.UUID, questionId uuid.UUID) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	questions, ok := lb.pending[eventId]
+	if !ok {
+		questions = map[uuid.UUID]struct{}{}
+		lb.pending[eventId] = questions
+	}
+	questions[questionId] = struct{}{}
+}
+
+func (lb *likeBatcher) run() {
+	ticker := time.NewTicker(lb.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		lb.mu.Lock()
+		pending := lb.pending
+		lb.pending = map[uuid.UUID]map[uuid.UUID]struct{}{}
+		lb.mu.Unlock()
+
+		for eventId, questions := range pending {
+			lb.flush(eventId, questions)
+		}
+	}
+}
+
+// flush sends the current like count of the questions to the event room
+func (lb *likeBatcher) flush(eventId uuid.UUID, questions map[uuid.UUID]struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	questionIds := make([]uuid.UUID, 0, len(questions))
+	for questionId := range questions {
+		questionIds = append(questionIds, questionId)
+	}
+
+	counts := []dtos.LikeCountResponse{}
+	countsResult := lb.DB.WithContext(ctx).Model(&models.Question{}).Select("question_id", "likes_count").Where("question_id IN ?", questionIds).Scan(&counts)
+	if countsResult.Error != nil {
+		log.Println("like batch err: ", countsResult.Error)
+		return
+	}
+
+	// every question in the batch could have been deleted in the meantime
+	if len(counts) == 0 {
+		return
+	}
+
+	lb.Room.Broadcast(eventId, dtos.WebSocketRespondJson(dtos.Like, dtos.LikeCountsType, counts))
+}
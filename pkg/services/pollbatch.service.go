@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/internal/config"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// the poll results are counted from the votes in the database, so every instance counts the same
+// while a poll is open the polls that got votes are gathered over a short window
+// and their results are sent to the event room once per window instead of once per vote
+
+const (
+	// how often the results of the polls that got votes are sent
+	pollResultsInterval = time.Second
+	// how many of the most answered terms of a text poll are sent
+	maxPollTerms = 100
+)
+
+type PollBatcher interface {
+	// Add marks the results of the poll as changed
+	Add(eventId uuid.UUID, pollId uuid.UUID)
+}
+
+type pollBatcher struct {
+	DB   *gorm.DB
+	Room RoomService
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]map[uuid.UUID]struct{}
+}
+
+func NewPollBatcher(db *gorm.DB, room RoomService) PollBatcher {
+	pb := &pollBatcher{
+		DB:      db,
+		Room:    room,
+		pending: map[uuid.UUID]map[uuid.UUID]struct{}{},
+	}
+
+	go pb.run()
+
+	return pb
+}
+
+// PollResults counts the votes of the poll, the options of the poll have to be loaded
+func PollResults(db *gorm.DB, poll *models.Poll) (dtos.PollResultsResponse, error) {
+	results := dtos.PollResultsResponse{
+		PollID:  poll.PollID,
+		EventID: poll.EventID,
+		Type:    poll.Type,
+	}
+
+	// answers waiting for the admin aren't counted yet
+	totalResult := db.Model(&models.PollVote{}).Where("poll_id = ? AND approved = ?", poll.PollID, true).Count(&results.TotalVotes)
+	if totalResult.Error != nil {
+		return results, totalResult.Error
+	}
+
+	switch poll.Type {
+	case models.RatingPoll:
+		ratings := []dtos.PollRatingResult{}
+		ratingsResult := db.Model(&models.PollVote{}).
+			Select("rating, COUNT(*) AS votes").
+			Where("poll_id = ? AND rating IS NOT NULL", poll.PollID).
+			Group("rating").
+			Scan(&ratings)
+		if ratingsResult.Error != nil {
+			return results, ratingsResult.Error
+		}
+
+		// every rating of the scale is listed, also the ones nobody picked
+		votes := map[int]int64{}
+		for _, rating := range ratings {
+			votes[rating.Rating] = rating.Votes
+		}
+
+		var sum, count int64
+		results.Ratings = make([]dtos.PollRatingResult, 0, poll.RatingMax)
+		for rating := 1; rating <= poll.RatingMax; rating++ {
+			results.Ratings = append(results.Ratings, dtos.PollRatingResult{Rating: rating, Votes: votes[rating]})
+			sum += int64(rating) * votes[rating]
+			count += votes[rating]
+		}
+
+		if count > 0 {
+			average := float64(sum) / float64(count)
+			results.Average = &average
+		}
+
+	case models.TextPoll:
+		results.Terms = []dtos.PollTermResult{}
+		termsResult := db.Model(&models.PollVote{}).
+			Select("term, COUNT(*) AS count").
+			Where("poll_id = ? AND approved = ? AND term <> ''", poll.PollID, true).
+			Group("term").
+			Order("count DESC, term ASC").
+			Limit(maxPollTerms).
+			Scan(&results.Terms)
+		if termsResult.Error != nil {
+			return results, termsResult.Error
+		}
+
+	default:
+		counts := []struct {
+			OptionID uuid.UUID
+			Votes    int64
+		}{}
+		countsResult := db.Table("poll_vote_options").
+			Select("poll_vote_options.option_id, COUNT(*) AS votes").
+			Joins("JOIN poll_votes ON poll_votes.vote_id = poll_vote_options.vote_id").
+			Where("poll_votes.poll_id = ?", poll.PollID).
+			Group("poll_vote_options.option_id").
+			Scan(&counts)
+		if countsResult.Error != nil {
+			return results, countsResult.Error
+		}
+
+		votes := map[uuid.UUID]int64{}
+		for _, count := range counts {
+			votes[count.OptionID] = count.Votes
+		}
+
+		results.Options = make([]dtos.PollOptionResult, 0, len(poll.Options))
+		for _, option := range poll.Options {
+			results.Options = append(results.Options, dtos.PollOptionResult{
+				OptionID: option.OptionID,
+				Content:  option.Content,
+				Votes:    votes[option.OptionID],
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// CurrentPollResults gives the results frozen when the poll was closed, or counts them while it's still open
+// the options of the poll have to be loaded
+func CurrentPollResults(db *gorm.DB, poll *models.Poll) (dtos.PollResultsResponse, error) {
+	if poll.Status == models.ClosedPoll && len(poll.Results) > 0 {
+		results := dtos.PollResultsResponse{}
+		err := json.Unmarshal(poll.Results, &results)
+		return results, err
+	}
+
+	return PollResults(db, poll)
+}
+
+func (pb *pollBatcher) Add(eventId uuid.UUID, pollId uuid.UUID) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	polls, ok := pb.pending[eventId]
+	if !ok {
+		polls = map[uuid.UUID]struct{}{}
+		pb.pending[eventId] = polls
+	}
+	polls[pollId] = struct{}{}
+}
+
+func (pb *pollBatcher) run() {
+	ticker := time.NewTicker(pollResultsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		pb.mu.Lock()
+		pending := pb.pending
+		pb.pending = map[uuid.UUID]map[uuid.UUID]struct{}{}
+		pb.mu.Unlock()
+
+		for eventId, polls := range pending {
+			pb.flush(eventId, polls)
+		}
+	}
+}
+
+// flush sends the current results of the polls to the event room
+func (pb *pollBatcher) flush(eventId uuid.UUID, polls map[uuid.UUID]struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	pollIds := make([]uuid.UUID, 0, len(polls))
+	for pollId := range polls {
+		pollIds = append(pollIds, pollId)
+	}
+
+	changedPolls := []models.Poll{}
+	pollsResult := pb.DB.WithContext(ctx).Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Where("poll_id IN ?", pollIds).Find(&changedPolls)
+	if pollsResult.Error != nil {
+		log.Println("poll results err: ", pollsResult.Error)
+		return
+	}
+
+	// a poll could have been deleted in the meantime
+	// one closed in the meantime keeps the results it was closed with, so the room and the stored results agree
+	for _, poll := range changedPolls {
+		results, err := CurrentPollResults(pb.DB.WithContext(ctx), &poll)
+		if err != nil {
+			log.Println("poll results err: ", err)
+			continue
+		}
+
+		pb.Room.Broadcast(eventId, dtos.WebSocketRespondJson(dtos.Poll, dtos.PollResultsType, results))
+	}
+}
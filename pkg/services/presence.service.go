@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/HudYuSa/mydeen/internal/config"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// with the postgres backend every instance counts the sessions of its own event rooms
+// it keeps its counts in room_presence, refreshed every presence interval
+// the audience of a room is the counts of this instance added to the rows of the other instances still running
+// the peak is the highest sum an instance saw, so it's kept in the rows too
+
+const (
+	// a row that wasn't refreshed for this long belongs to an instance that stopped
+	presenceStale = 5 * presenceInterval
+	// the rows of stopped instances are deleted after this long
+	presenceRetention = time.Hour
+)
+
+type presenceStore struct {
+	DB *gorm.DB
+	// every instance gets its own id when it starts
+	InstanceID uuid.UUID
+}
+
+// roomPresence is the row of one instance for one event room
+type roomPresence struct {
+	EventID uuid.UUID
+	Current int
+	Peak    int
+	PeakAt  *time.Time
+	Joins   int
+	Leaves  int
+	Trend   string
+}
+
+func newPresenceStore(db *gorm.DB) *presenceStore {
+	return &presenceStore{
+		DB:         db,
+		InstanceID: uuid.New(),
+	}
+}
+
+// save refreshes the rows of this instance with the counts of its rooms
+func (ps *presenceStore) save(counts []dtos.PresenceResponse) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	return ps.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, count := range counts {
+			saveResult := tx.Exec(`INSERT INTO "room_presence" ("instance_id", "event_id", "current", "peak", "peak_at", "joins", "leaves", "trend", "updated_at")
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?::jsonb, ?)
+				ON CONFLICT ("instance_id", "event_id") DO UPDATE SET
+					"current" = EXCLUDED."current", "peak" = EXCLUDED."peak", "peak_at" = EXCLUDED."peak_at",
+					"joins" = EXCLUDED."joins", "leaves" = EXCLUDED."leaves", "trend" = EXCLUDED."trend", "updated_at" = EXCLUDED."updated_at"`,
+				ps.InstanceID, count.EventID, count.Current, count.Peak, count.PeakAt, count.Joins, count.Leaves, string(dtos.EncodeJson(count.Trend)), now)
+			if saveResult.Error != nil {
+				return saveResult.Error
+			}
+		}
+
+		cleanResult := tx.Exec(`DELETE FROM "room_presence" WHERE "updated_at" < ?`, now.Add(-presenceRetention))
+		return cleanResult.Error
+	})
+}
+
+// others loads the counts of the other running instances for the event rooms
+func (ps *presenceStore) others(eventIds []uuid.UUID) (map[uuid.UUID][]roomPresence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GlobalConfig.DatabaseTimeout)*time.Millisecond)
+	defer cancel()
+
+	rows := []roomPresence{}
+	othersResult := ps.DB.WithContext(ctx).Raw(`SELECT "event_id", "current", "peak", "peak_at", "joins", "leaves", "trend" FROM "room_presence"
+		WHERE "event_id" IN ? AND "instance_id" <> ? AND "updated_at" > ?`,
+		eventIds, ps.InstanceID, time.Now().UTC().Add(-presenceStale)).Scan(&rows)
+	if othersResult.Error != nil {
+		return nil, othersResult.Error
+	}
+
+	others := map[uuid.UUID][]roomPresence{}
+	for _, row := range rows {
+		others[row.EventID] = append(others[row.EventID], row)
+	}
+
+	return others, nil
+}
+
+// addPresence adds the counts of the other instances to the counts of this instance
+// the trend is added up minute by minute and the peak is the highest any instance saw
+func addPresence(count dtos.PresenceResponse, others []roomPresence) dtos.PresenceResponse {
+	// the minutes are compared by their unix time, the ones read back from json have another location
+	minutes := map[int64]dtos.PresenceTrendResponse{}
+	for _, bucket := range count.Trend {
+		minutes[bucket.Minute.Unix()] = bucket
+	}
+
+	for _, other := range others {
+		count.Current += other.Current
+		count.Joins += other.Joins
+		count.Leaves += other.Leaves
+
+		if other.Peak > count.Peak && other.PeakAt != nil {
+			count.Peak = other.Peak
+			count.PeakAt = other.PeakAt
+		}
+
+		trend := []dtos.PresenceTrendResponse{}
+		if err := json.Unmarshal([]byte(other.Trend), &trend); err != nil {
+			continue
+		}
+		for _, bucket := range trend {
+			sum, ok := minutes[bucket.Minute.Unix()]
+			if !ok {
+				sum.Minute = bucket.Minute.UTC()
+			}
+			sum.Joins += bucket.Joins
+			sum.Leaves += bucket.Leaves
+			minutes[bucket.Minute.Unix()] = sum
+		}
+	}
+
+	count.Trend = make([]dtos.PresenceTrendResponse, 0, len(minutes))
+	for _, bucket := range minutes {
+		count.Trend = append(count.Trend, bucket)
+	}
+	sort.Slice(count.Trend, func(i, j int) bool {
+		return count.Trend[i].Minute.Before(count.Trend[j].Minute)
+	})
+	if len(count.Trend) > presenceTrendMinutes {
+		count.Trend = count.Trend[len(count.Trend)-presenceTrendMinutes:]
+	}
+
+	// the instances saw their peaks at other times, the audience now can be higher than all of them
+	if count.Current > count.Peak {
+		now := time.Now().UTC()
+		count.Peak = count.Current
+		count.PeakAt = &now
+	}
+
+	return count
+}
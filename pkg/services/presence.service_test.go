@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/google/uuid"
+)
+
+func TestAddPresence(t *testing.T) {
+	eventId := uuid.New()
+	minute := time.Date(2023, 10, 5, 14, 30, 0, 0, time.UTC)
+	earlier := minute.Add(-10 * time.Minute)
+
+	tests := []struct {
+		name   string
+		count  dtos.PresenceResponse
+		others []roomPresence
+		want   dtos.PresenceResponse
+	}{
+		{
+			"no other instance",
+			dtos.PresenceResponse{Current: 3, Peak: 5, PeakAt: &earlier, Joins: 6, Leaves: 3, Trend: []dtos.PresenceTrendResponse{{Minute: minute, Joins: 6, Leaves: 3}}},
+			nil,
+			dtos.PresenceResponse{Current: 3, Peak: 5, PeakAt: &earlier, Joins: 6, Leaves: 3, Trend: []dtos.PresenceTrendResponse{{Minute: minute, Joins: 6, Leaves: 3}}},
+		},
+		{
+			"counts and trend are added up",
+			dtos.PresenceResponse{Current: 3, Peak: 10, PeakAt: &earlier, Joins: 6, Leaves: 3, Trend: []dtos.PresenceTrendResponse{{Minute: minute, Joins: 6, Leaves: 3}}},
+			[]roomPresence{
+				{Current: 2, Peak: 4, PeakAt: &earlier, Joins: 3, Leaves: 1, Trend: `[{"minute":"2023-10-05T14:20:00Z","joins":1,"leaves":0},{"minute":"2023-10-05T14:30:00Z","joins":2,"leaves":1}]`},
+				{Current: 1, Peak: 1, PeakAt: &earlier, Joins: 1, Leaves: 0, Trend: `[{"minute":"2023-10-05T14:30:00Z","joins":1,"leaves":0}]`},
+			},
+			dtos.PresenceResponse{Current: 6, Peak: 10, PeakAt: &earlier, Joins: 10, Leaves: 4, Trend: []dtos.PresenceTrendResponse{
+				{Minute: earlier, Joins: 1, Leaves: 0},
+				{Minute: minute, Joins: 9, Leaves: 4},
+			}},
+		},
+		{
+			"another instance saw a higher peak",
+			dtos.PresenceResponse{Current: 1, Peak: 2, PeakAt: &minute, Trend: []dtos.PresenceTrendResponse{}},
+			[]roomPresence{{Current: 1, Peak: 8, PeakAt: &earlier, Trend: `[]`}},
+			dtos.PresenceResponse{Current: 2, Peak: 8, PeakAt: &earlier, Trend: []dtos.PresenceTrendResponse{}},
+		},
+		{
+			"a broken trend is skipped",
+			dtos.PresenceResponse{Current: 1, Peak: 5, PeakAt: &minute, Joins: 1, Trend: []dtos.PresenceTrendResponse{}},
+			[]roomPresence{{Current: 1, Peak: 1, PeakAt: &minute, Joins: 1, Trend: `{`}},
+			dtos.PresenceResponse{Current: 2, Peak: 5, PeakAt: &minute, Joins: 2, Trend: []dtos.PresenceTrendResponse{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.count.EventID = eventId
+			got := addPresence(tt.count, tt.others)
+
+			if got.Current != tt.want.Current || got.Peak != tt.want.Peak || got.Joins != tt.want.Joins || got.Leaves != tt.want.Leaves {
+				t.Errorf("counts = %d/%d/%d/%d, want %d/%d/%d/%d", got.Current, got.Peak, got.Joins, got.Leaves, tt.want.Current, tt.want.Peak, tt.want.Joins, tt.want.Leaves)
+			}
+			if got.PeakAt == nil || !got.PeakAt.Equal(*tt.want.PeakAt) {
+				t.Errorf("peak at = %v, want %v", got.PeakAt, tt.want.PeakAt)
+			}
+			if len(got.Trend) != len(tt.want.Trend) {
+				t.Fatalf("trend = %+v, want %+v", got.Trend, tt.want.Trend)
+			}
+			for i, bucket := range got.Trend {
+				want := tt.want.Trend[i]
+				if !bucket.Minute.Equal(want.Minute) || bucket.Joins != want.Joins || bucket.Leaves != want.Leaves {
+					t.Errorf("trend %d = %+v, want %+v", i, bucket, want)
+				}
+			}
+		})
+	}
+
+	t.Run("the sum is higher than every peak", func(t *testing.T) {
+		got := addPresence(dtos.PresenceResponse{Current: 4, Peak: 4, PeakAt: &earlier}, []roomPresence{{Current: 3, Peak: 3, PeakAt: &earlier, Trend: `[]`}})
+		if got.Peak != 7 || got.PeakAt == nil || !got.PeakAt.After(earlier) {
+			t.Errorf("peak = %d at %v, want 7 now", got.Peak, got.PeakAt)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+)
+
+// the rate limiter keeps a token bucket for every key and message type
+// a key is a websocket session or a participant, so one participant can't get around it with more sockets
+// every message type has its own budget, types without one use the default budget
+
+// the budget name used for every message type without its own budget
+const DefaultRateBudget = "default"
+
+const (
+	// buckets that weren't used for this long are dropped
+	rateBucketIdle = 10 * time.Minute
+	// how often the idle buckets are dropped
+	rateSweepInterval = time.Minute
+)
+
+// RateBudget allows Burst messages per Per, refilled continuously
+type RateBudget struct {
+	Burst int
+	Per   time.Duration
+}
+
+// the budgets used when the config doesn't set them
+var defaultRateBudgets = map[string]RateBudget{
+	DefaultRateBudget:               {Burst: 20, Per: 10 * time.Second},
+	string(dtos.CreateQuestionType): {Burst: 3, Per: 10 * time.Second},
+	string(dtos.EditQuestionType):   {Burst: 5, Per: 10 * time.Second},
+	string(dtos.DeleteQuestionType): {Burst: 5, Per: 10 * time.Second},
+	string(dtos.ToggleLikeType):     {Burst: 10, Per: 5 * time.Second},
+	string(dtos.VotePollType):       {Burst: 5, Per: 10 * time.Second},
+	string(dtos.AnswerQuizType):     {Burst: 5, Per: 10 * time.Second},
+	string(dtos.JoinRoomType):       {Burst: 5, Per: 10 * time.Second},
+}
+
+type RateLimiter interface {
+	// Allow takes one message of the type from the budget of every key
+	// when one of them is used up nothing is taken and it returns how long to wait
+	Allow(t dtos.WebSocketType, keys ...string) (bool, time.Duration)
+	// Forget drops the buckets of a key, for a session that disconnected
+	Forget(key string)
+}
+
+type rateLimiter struct {
+	budgets map[string]RateBudget
+
+	mu      sync.Mutex
+	buckets map[string]map[string]*rateBucket
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// ParseRateBudgets reads budgets like "createQuestion=3/10s,toggleLike=10/5s,default=20/10s"
+// the budgets that aren't given keep their defaults
+func ParseRateBudgets(config string) (map[string]RateBudget, error) {
+	budgets := map[string]RateBudget{}
+	for name, budget := range defaultRateBudgets {
+		budgets[name] = budget
+	}
+
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate budget %q", entry)
+		}
+
+		burst, per, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate budget %q", entry)
+		}
+
+		burstCount, err := strconv.Atoi(strings.TrimSpace(burst))
+		if err != nil || burstCount < 1 {
+			return nil, fmt.Errorf("invalid rate budget %q", entry)
+		}
+
+		perDuration, err := time.ParseDuration(strings.TrimSpace(per))
+		if err != nil || perDuration <= 0 {
+			return nil, fmt.Errorf("invalid rate budget %q", entry)
+		}
+
+		budgets[strings.TrimSpace(name)] = RateBudget{Burst: burstCount, Per: perDuration}
+	}
+
+	return budgets, nil
+}
+
+func NewRateLimiter(budgets map[string]RateBudget) RateLimiter {
+	rl := &rateLimiter{
+		budgets: budgets,
+		buckets: map[string]map[string]*rateBucket{},
+	}
+
+	go rl.sweep()
+
+	return rl
+}
+
+func (rl *rateLimiter) Allow(t dtos.WebSocketType, keys ...string) (bool, time.Duration) {
+	name := string(t)
+	budget, ok := rl.budgets[name]
+	if !ok {
+		name = DefaultRateBudget
+		budget = rl.budgets[DefaultRateBudget]
+	}
+
+	// a refill rate of tokens per second
+	rate := float64(budget.Burst) / budget.Per.Seconds()
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	buckets := make([]*rateBucket, 0, len(keys))
+	var wait time.Duration
+	for _, key := range keys {
+		bucket := rl.bucket(key, name, budget, now)
+		bucket.tokens = math.Min(float64(budget.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+		bucket.last = now
+
+		if bucket.tokens < 1 {
+			if bucketWait := time.Duration((1 - bucket.tokens) / rate * float64(time.Second)); bucketWait > wait {
+				wait = bucketWait
+			}
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+
+	return true, 0
+}
+
+func (rl *rateLimiter) Forget(key string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	delete(rl.buckets, key)
+}
+
+// bucket returns the bucket of the key for the budget, rl.mu has to be held
+func (rl *rateLimiter) bucket(key string, name string, budget RateBudget, now time.Time) *rateBucket {
+	keyBuckets, ok := rl.buckets[key]
+	if !ok {
+		keyBuckets = map[string]*rateBucket{}
+		rl.buckets[key] = keyBuckets
+	}
+
+	bucket, ok := keyBuckets[name]
+	if !ok {
+		bucket = &rateBucket{tokens: float64(budget.Burst), last: now}
+		keyBuckets[name] = bucket
+	}
+
+	return bucket
+}
+
+// sweep drops the buckets nobody used for a while, a full bucket is the same as no bucket
+func (rl *rateLimiter) sweep() {
+	ticker := time.NewTicker(rateSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+
+		rl.mu.Lock()
+		for key, keyBuckets := range rl.buckets {
+			for name, bucket := range keyBuckets {
+				if now.Sub(bucket.last) > rateBucketIdle {
+					delete(keyBuckets, name)
+				}
+			}
+			if len(keyBuckets) == 0 {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.mu.Unlock()
+	}
+}
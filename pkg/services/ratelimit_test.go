@@ -0,0 +1,163 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+)
+
+func TestParseRateBudgets(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    map[string]RateBudget
+		wantErr bool
+	}{
+		{"empty keeps the defaults", "", map[string]RateBudget{}, false},
+		{"one budget", "createQuestion=1/1m", map[string]RateBudget{
+			string(dtos.CreateQuestionType): {Burst: 1, Per: time.Minute},
+		}, false},
+		{"several budgets with spaces", " toggleLike = 2/3s , default=50/1m,", map[string]RateBudget{
+			string(dtos.ToggleLikeType): {Burst: 2, Per: 3 * time.Second},
+			DefaultRateBudget:           {Burst: 50, Per: time.Minute},
+		}, false},
+		{"new message type", "sendAnswer=4/2s", map[string]RateBudget{
+			"sendAnswer": {Burst: 4, Per: 2 * time.Second},
+		}, false},
+		{"missing equals", "createQuestion", nil, true},
+		{"missing slash", "createQuestion=3", nil, true},
+		{"burst not a number", "createQuestion=a/10s", nil, true},
+		{"zero burst", "createQuestion=0/10s", nil, true},
+		{"invalid duration", "createQuestion=3/10", nil, true},
+		{"zero duration", "createQuestion=3/0s", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budgets, err := ParseRateBudgets(tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q", tt.config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the budgets that aren't given keep their defaults
+			want := map[string]RateBudget{}
+			for name, budget := range defaultRateBudgets {
+				want[name] = budget
+			}
+			for name, budget := range tt.want {
+				want[name] = budget
+			}
+
+			if len(budgets) != len(want) {
+				t.Errorf("got %d budgets, want %d", len(budgets), len(want))
+			}
+			for name, budget := range want {
+				if budgets[name] != budget {
+					t.Errorf("budget %q = %+v, want %+v", name, budgets[name], budget)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	// a long period so nothing refills while the test runs
+	budgets := map[string]RateBudget{
+		DefaultRateBudget:               {Burst: 3, Per: time.Hour},
+		string(dtos.CreateQuestionType): {Burst: 2, Per: time.Hour},
+	}
+
+	type call struct {
+		t       dtos.WebSocketType
+		keys    []string
+		allowed bool
+	}
+
+	tests := []struct {
+		name  string
+		calls []call
+	}{
+		{"burst then limited", []call{
+			{dtos.CreateQuestionType, []string{"s1"}, true},
+			{dtos.CreateQuestionType, []string{"s1"}, true},
+			{dtos.CreateQuestionType, []string{"s1"}, false},
+		}},
+		{"keys have their own buckets", []call{
+			{dtos.CreateQuestionType, []string{"s1"}, true},
+			{dtos.CreateQuestionType, []string{"s1"}, true},
+			{dtos.CreateQuestionType, []string{"s2"}, true},
+		}},
+		{"types have their own budgets", []call{
+			{dtos.CreateQuestionType, []string{"s1"}, true},
+			{dtos.CreateQuestionType, []string{"s1"}, true},
+			{dtos.ToggleLikeType, []string{"s1"}, true},
+		}},
+		{"types without a budget use the default", []call{
+			{dtos.ToggleLikeType, []string{"s1"}, true},
+			{dtos.ToggleLikeType, []string{"s1"}, true},
+			{dtos.ToggleLikeType, []string{"s1"}, true},
+			{dtos.ToggleLikeType, []string{"s1"}, false},
+		}},
+		{"a participant is limited across sessions", []call{
+			{dtos.CreateQuestionType, []string{"s1", "u1"}, true},
+			{dtos.CreateQuestionType, []string{"s2", "u1"}, true},
+			{dtos.CreateQuestionType, []string{"s3", "u1"}, false},
+		}},
+		{"nothing is taken when one key is used up", []call{
+			{dtos.CreateQuestionType, []string{"u1"}, true},
+			{dtos.CreateQuestionType, []string{"u1"}, true},
+			{dtos.CreateQuestionType, []string{"s1", "u1"}, false},
+			{dtos.CreateQuestionType, []string{"s1"}, true},
+			{dtos.CreateQuestionType, []string{"s1"}, true},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := &rateLimiter{
+				budgets: budgets,
+				buckets: map[string]map[string]*rateBucket{},
+			}
+
+			for i, c := range tt.calls {
+				allowed, wait := rl.Allow(c.t, c.keys...)
+				if allowed != c.allowed {
+					t.Fatalf("call %d: allowed = %v, want %v", i, allowed, c.allowed)
+				}
+				if allowed && wait != 0 {
+					t.Errorf("call %d: wait = %v for an allowed message", i, wait)
+				}
+				if !allowed && wait <= 0 {
+					t.Errorf("call %d: wait = %v for a limited message", i, wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterForget(t *testing.T) {
+	rl := &rateLimiter{
+		budgets: map[string]RateBudget{DefaultRateBudget: {Burst: 1, Per: time.Hour}},
+		buckets: map[string]map[string]*rateBucket{},
+	}
+
+	if allowed, _ := rl.Allow(dtos.ToggleLikeType, "s1"); !allowed {
+		t.Fatal("first message was limited")
+	}
+	if allowed, _ := rl.Allow(dtos.ToggleLikeType, "s1"); allowed {
+		t.Fatal("second message wasn't limited")
+	}
+
+	rl.Forget("s1")
+
+	if allowed, _ := rl.Allow(dtos.ToggleLikeType, "s1"); !allowed {
+		t.Fatal("message after forget was limited")
+	}
+}
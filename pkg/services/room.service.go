@@ -0,0 +1,618 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/google/uuid"
+	"github.com/olahol/melody"
+)
+
+// every websocket session can be bound to exactly one event room
+// messages about an event are only sent to the sessions inside its room
+// every message sent to a room gets the next sequence number of that room
+// so a client that reconnects can ask for the messages it missed
+// messages go through the broadcast backend so the rooms of every instance get them
+// presence is counted by every instance, with the postgres backend the counts of every instance are added up
+// read-only subscribers, like the server-sent events feed, get the messages meant for everyone
+
+const (
+	roomKey = "eventId"
+	// the sequence the session joined the room at, the messages up to it reach the session through the replay
+	roomSeqKey = "roomSeq"
+)
+
+const (
+	// presence updates are pushed at most once per interval for every room
+	presenceInterval = 2 * time.Second
+	// how many minutes of join/leave trend are kept for every room
+	presenceTrendMinutes = 30
+	// how many of the latest messages are kept for every room to be replayed
+	// a client that missed more than this has to load a snapshot instead
+	roomLogSize = 100
+	// how many messages a subscriber can fall behind before it's dropped
+	subscriptionBufferSize = 256
+)
+
+type RoomService interface {
+	Join(s *melody.Session, eventId uuid.UUID, lastSeq *uint64, joined func(seq uint64) []byte) bool
+	Leave(s *melody.Session)
+	EventID(s *melody.Session) (uuid.UUID, bool)
+	Broadcast(eventId uuid.UUID, msg []byte) error
+	BroadcastTo(eventId uuid.UUID, msg []byte, audience Audience) error
+	Seq(eventId uuid.UUID) uint64
+	Presence(eventId uuid.UUID) dtos.PresenceResponse
+	Subscribe(eventId uuid.UUID) *RoomSubscription
+	Unsubscribe(sub *RoomSubscription)
+	Missed(eventId uuid.UUID, lastSeq uint64) ([]RoomMessage, bool)
+}
+
+type roomService struct {
+	Melody      *melody.Melody
+	Broadcaster Broadcaster
+	// the counts of the other instances, only with a backend that runs more than one instance
+	presenceStore *presenceStore
+
+	mu    sync.Mutex
+	rooms map[uuid.UUID]*room
+}
+
+// room is the state of one event room on this instance
+type room struct {
+	// the latest sequence delivered to the room on this instance
+	seq         uint64
+	log         []roomMessage
+	presence    presence
+	subscribers map[*RoomSubscription]struct{}
+}
+
+// RoomMessage is a sequenced message of a room
+type RoomMessage struct {
+	Seq uint64
+	Msg []byte
+}
+
+// RoomSubscription receives the messages of a room meant for everyone
+// C is closed when the subscriber fell too far behind, it has to resume from its last sequence
+type RoomSubscription struct {
+	EventID uuid.UUID
+	C       <-chan RoomMessage
+
+	c chan RoomMessage
+}
+
+// roomMessage is a sequenced message kept for replay
+// the audience is kept so a replay only sends what the session was allowed to see
+type roomMessage struct {
+	seq      uint64
+	msg      []byte
+	audience Audience
+}
+
+// presence is the audience of one event room
+type presence struct {
+	current int
+	peak    int
+	peakAt  time.Time
+	joins   int
+	leaves  int
+	trend   []dtos.PresenceTrendResponse
+	// dirty is set when the room changed since the last pushed update
+	dirty bool
+	// the highest audience of every instance together this instance saw
+	sumPeak   int
+	sumPeakAt time.Time
+	// the audience of every instance together last pushed to the admins
+	pushed dtos.PresenceResponse
+}
+
+func NewRoomService(m *melody.Melody, broadcaster Broadcaster) RoomService {
+	rs := &roomService{
+		Melody:      m,
+		Broadcaster: broadcaster,
+		rooms:       map[uuid.UUID]*room{},
+	}
+
+	// the memory backend only works with a single instance, so it sees every session by itself
+	if pb, ok := broadcaster.(*postgresBroadcaster); ok {
+		rs.presenceStore = newPresenceStore(pb.DB)
+	}
+
+	broadcaster.Listen(rs.deliver)
+	go rs.pushPresence()
+
+	return rs
+}
+
+// Join binds the session to the event room, replacing the previous room if any
+// joined builds the join response from the sequence the session starts at, it's written before any message of the room
+// with a lastSeq the messages the session missed up to that sequence are replayed right after the join response
+// it returns false when some of them aren't kept anymore and the client needs a snapshot
+func (rs *roomService) Join(s *melody.Session, eventId uuid.UUID, lastSeq *uint64, joined func(seq uint64) []byte) bool {
+	rs.Leave(s)
+
+	// read before locking the rooms, the broadcast backend holds its own lock while it delivers
+	seq := rs.Seq(eventId)
+
+	// deliver holds the same lock, so no message reaches the session before it's caught up
+	// and the messages it gets live are the ones after its join sequence
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r := rs.room(eventId)
+
+	// the session starts after the latest message delivered here, the ones still on their way come live
+	// a room that got nothing here yet starts at the backend sequence, what came before can't be replayed anyway
+	joinSeq := r.seq
+	if joinSeq == 0 {
+		joinSeq = seq
+	}
+
+	s.Set(roomKey, eventId)
+	s.Set(roomSeqKey, joinSeq)
+	rs.track(r, 1)
+
+	s.Write(joined(joinSeq))
+
+	if lastSeq == nil {
+		return true
+	}
+
+	// the sequence was reset, the client has a sequence we never handed out
+	if *lastSeq > joinSeq {
+		return false
+	}
+
+	missed, ok := r.missed(*lastSeq, joinSeq)
+	if !ok {
+		return false
+	}
+
+	for _, message := range missed {
+		if message.audience.includes(s) {
+			s.Write(message.msg)
+		}
+	}
+
+	return true
+}
+
+// Leave removes the session from its current room
+func (rs *roomService) Leave(s *melody.Session) {
+	eventId, ok := rs.EventID(s)
+	if !ok {
+		return
+	}
+
+	s.UnSet(roomKey)
+	s.UnSet(roomSeqKey)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.track(rs.room(eventId), -1)
+}
+
+// EventID returns the event the session is currently bound to
+func (rs *roomService) EventID(s *melody.Session) (uuid.UUID, bool) {
+	value, exists := s.Get(roomKey)
+	if !exists {
+		return uuid.Nil, false
+	}
+
+	eventId, ok := value.(uuid.UUID)
+	return eventId, ok
+}
+
+// Broadcast sends the message only to the sessions inside the event room
+func (rs *roomService) Broadcast(eventId uuid.UUID, msg []byte) error {
+	return rs.BroadcastTo(eventId, msg, Audience{})
+}
+
+// BroadcastTo sends the message to the sessions inside the event room that belong to the audience
+func (rs *roomService) BroadcastTo(eventId uuid.UUID, msg []byte, audience Audience) error {
+	err := rs.Broadcaster.Publish(eventId, msg, audience)
+	if err != nil {
+		log.Println("room broadcast err: ", err)
+	}
+	return err
+}
+
+// Seq returns the sequence number of the latest message sent to the event room
+func (rs *roomService) Seq(eventId uuid.UUID) uint64 {
+	seq, err := rs.Broadcaster.Seq(eventId)
+	if err != nil {
+		log.Println("room seq err: ", err)
+	}
+	return seq
+}
+
+// Missed returns the messages meant for everyone sent to the event room after lastSeq
+// it returns false when some of them aren't kept anymore and the client needs a snapshot
+func (rs *roomService) Missed(eventId uuid.UUID, lastSeq uint64) ([]RoomMessage, bool) {
+	seq := rs.Seq(eventId)
+
+	// the sequence was reset, the client has a sequence we never handed out
+	if lastSeq > seq {
+		return nil, false
+	}
+
+	rs.mu.Lock()
+	r, ok := rs.rooms[eventId]
+	if !ok {
+		rs.mu.Unlock()
+		// nothing was sent here, only a client that is current can carry on
+		return []RoomMessage{}, lastSeq == seq
+	}
+	missed, ok := r.missed(lastSeq, seq)
+	rs.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	messages := []RoomMessage{}
+	for _, message := range missed {
+		if message.audience.public() {
+			messages = append(messages, RoomMessage{Seq: message.seq, Msg: message.msg})
+		}
+	}
+
+	return messages, true
+}
+
+// missed returns the kept messages of the room after lastSeq up to upTo, rs.mu has to be held
+func (r *room) missed(lastSeq uint64, upTo uint64) ([]roomMessage, bool) {
+	// nothing was missed
+	if lastSeq >= upTo {
+		return []roomMessage{}, true
+	}
+
+	// the oldest missed message was already dropped, or was sent before this instance started
+	if len(r.log) == 0 || r.log[0].seq > lastSeq+1 {
+		return nil, false
+	}
+
+	missed := []roomMessage{}
+	for _, message := range r.log {
+		if message.seq > lastSeq && message.seq <= upTo {
+			missed = append(missed, message)
+		}
+	}
+
+	return missed, true
+}
+
+// Subscribe starts sending the messages of the event room meant for everyone to the subscription
+func (rs *roomService) Subscribe(eventId uuid.UUID) *RoomSubscription {
+	c := make(chan RoomMessage, subscriptionBufferSize)
+	sub := &RoomSubscription{
+		EventID: eventId,
+		C:       c,
+		c:       c,
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r := rs.room(eventId)
+	if r.subscribers == nil {
+		r.subscribers = map[*RoomSubscription]struct{}{}
+	}
+	r.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+// Unsubscribe stops the subscription, it's safe to call after it was dropped
+func (rs *roomService) Unsubscribe(sub *RoomSubscription) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.drop(sub)
+}
+
+// Presence returns the current and peak audience of the event room on every instance
+func (rs *roomService) Presence(eventId uuid.UUID) dtos.PresenceResponse {
+	rs.mu.Lock()
+	count := dtos.PresenceResponse{
+		EventID: eventId,
+		Scope:   dtos.AllPresence,
+		Trend:   []dtos.PresenceTrendResponse{},
+	}
+	if r, ok := rs.rooms[eventId]; ok {
+		count = r.presence.response(eventId, dtos.AllPresence)
+	}
+	rs.mu.Unlock()
+
+	if rs.presenceStore == nil {
+		return count
+	}
+
+	others, err := rs.presenceStore.others([]uuid.UUID{eventId})
+	if err != nil {
+		log.Println("presence err: ", err)
+		// only the sessions of this instance can be counted
+		count.Scope = dtos.InstancePresence
+		return count
+	}
+
+	return rs.sumPresence(count, others[eventId])
+}
+
+// sumPresence adds the counts of the other instances to the count of this instance and keeps the peak of the sum
+func (rs *roomService) sumPresence(count dtos.PresenceResponse, others []roomPresence) dtos.PresenceResponse {
+	sum := addPresence(count, others)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if r, ok := rs.rooms[sum.EventID]; ok && sum.Peak > r.presence.sumPeak && sum.PeakAt != nil {
+		r.presence.sumPeak = sum.Peak
+		r.presence.sumPeakAt = *sum.PeakAt
+	}
+
+	return sum
+}
+
+// deliver gets the messages from the broadcast backend and writes them to the sessions of this instance
+func (rs *roomService) deliver(eventId uuid.UUID, seq uint64, msg []byte, audience Audience) {
+	// the lock is held while sending so the room gets its messages in sequence order
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	msg = dtos.SetWebSocketSeq(msg, seq)
+
+	r := rs.room(eventId)
+	if seq > r.seq {
+		r.seq = seq
+	}
+	r.log = append(r.log, roomMessage{seq: seq, msg: msg, audience: audience})
+	if len(r.log) > roomLogSize {
+		r.log = r.log[len(r.log)-roomLogSize:]
+	}
+
+	rs.send(eventId, seq, msg, audience)
+
+	if !audience.public() {
+		return
+	}
+
+	for sub := range r.subscribers {
+		select {
+		case sub.c <- RoomMessage{Seq: seq, Msg: msg}:
+		default:
+			// a subscriber that can't keep up would block the room
+			rs.drop(sub)
+		}
+	}
+}
+
+// drop removes the subscription from its room and closes it, rs.mu has to be held
+func (rs *roomService) drop(sub *RoomSubscription) {
+	r, ok := rs.rooms[sub.EventID]
+	if !ok {
+		return
+	}
+
+	if _, ok := r.subscribers[sub]; ok {
+		delete(r.subscribers, sub)
+		close(sub.c)
+	}
+}
+
+// send writes the message to the sessions of the room on this instance without sequencing it
+// a session that joined at or after seq already got the message in its replay, a seq of 0 goes to every session
+func (rs *roomService) send(eventId uuid.UUID, seq uint64, msg []byte, audience Audience) error {
+	return rs.Melody.BroadcastFilter(msg, func(q *melody.Session) bool {
+		id, ok := rs.EventID(q)
+		if !ok || id != eventId || !audience.includes(q) {
+			return false
+		}
+
+		if value, exists := q.Get(roomSeqKey); exists && seq > 0 {
+			if joinSeq, ok := value.(uint64); ok && seq <= joinSeq {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// room returns the state of the event room, rs.mu has to be held
+func (rs *roomService) room(eventId uuid.UUID) *room {
+	r, ok := rs.rooms[eventId]
+	if !ok {
+		r = &room{}
+		rs.rooms[eventId] = r
+	}
+
+	return r
+}
+
+// track counts a session joining (1) or leaving (-1) the room, rs.mu has to be held
+func (rs *roomService) track(r *room, delta int) {
+	p := &r.presence
+
+	now := time.Now().UTC()
+	minute := now.Truncate(time.Minute)
+
+	// start a new trend bucket every minute and drop the old ones
+	if len(p.trend) == 0 || !p.trend[len(p.trend)-1].Minute.Equal(minute) {
+		p.trend = append(p.trend, dtos.PresenceTrendResponse{Minute: minute})
+		if len(p.trend) > presenceTrendMinutes {
+			p.trend = p.trend[len(p.trend)-presenceTrendMinutes:]
+		}
+	}
+	bucket := &p.trend[len(p.trend)-1]
+
+	p.current += delta
+	if delta > 0 {
+		p.joins++
+		bucket.Joins++
+	} else {
+		p.leaves++
+		bucket.Leaves++
+	}
+
+	if p.current > p.peak {
+		p.peak = p.current
+		p.peakAt = now
+	}
+
+	p.dirty = true
+}
+
+// pushPresence sends the audience count to the admins in the rooms that changed, throttled by presenceInterval
+// presence updates aren't sequenced, a reconnecting client gets a fresh one anyway
+func (rs *roomService) pushPresence() {
+	ticker := time.NewTicker(presenceInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		updates := map[uuid.UUID]dtos.PresenceResponse{}
+		counts := []dtos.PresenceResponse{}
+
+		now := time.Now().UTC()
+
+		rs.mu.Lock()
+		for eventId, r := range rs.rooms {
+			// the rooms with sessions or a recent trend are saved every time so their rows don't go stale
+			p := &r.presence
+			recent := len(p.trend) > 0 && now.Sub(p.trend[len(p.trend)-1].Minute) < presenceTrendMinutes*time.Minute
+			if p.current > 0 || p.dirty || recent {
+				counts = append(counts, r.presence.response(eventId, dtos.AllPresence))
+			}
+			if r.presence.dirty {
+				r.presence.dirty = false
+				updates[eventId] = r.presence.response(eventId, dtos.AllPresence)
+			}
+		}
+		rs.mu.Unlock()
+
+		if rs.presenceStore != nil {
+			rs.syncPresence(counts, updates)
+		}
+
+		for eventId, update := range updates {
+			rs.send(eventId, 0, dtos.WebSocketRespondJson(dtos.Room, dtos.PresenceType, update), Audience{Roles: []dtos.Role{dtos.AdminRole}})
+		}
+	}
+}
+
+// syncPresence saves the counts of this instance and pushes the audience of every instance together
+// a room gets an update when the sum changed, also when only another instance's count did
+func (rs *roomService) syncPresence(counts []dtos.PresenceResponse, updates map[uuid.UUID]dtos.PresenceResponse) {
+	if err := rs.presenceStore.save(counts); err != nil {
+		log.Println("presence err: ", err)
+	}
+
+	// the admins that get the updates are in the rooms with sessions on this instance
+	eventIds := []uuid.UUID{}
+	for _, count := range counts {
+		if count.Current > 0 {
+			eventIds = append(eventIds, count.EventID)
+		}
+	}
+	if len(eventIds) == 0 {
+		return
+	}
+
+	others, err := rs.presenceStore.others(eventIds)
+	if err != nil {
+		log.Println("presence err: ", err)
+		for eventId, update := range updates {
+			update.Scope = dtos.InstancePresence
+			updates[eventId] = update
+		}
+		return
+	}
+
+	for _, count := range counts {
+		if count.Current == 0 {
+			continue
+		}
+
+		sum := rs.sumPresence(count, others[count.EventID])
+
+		rs.mu.Lock()
+		r := rs.room(count.EventID)
+		pushed := r.presence.pushed
+		changed := sum.Current != pushed.Current || sum.Peak != pushed.Peak || sum.Joins != pushed.Joins || sum.Leaves != pushed.Leaves
+		if changed {
+			r.presence.pushed = sum
+		}
+		rs.mu.Unlock()
+
+		if _, ok := updates[count.EventID]; ok || changed {
+			updates[count.EventID] = sum
+		}
+	}
+}
+
+func (p *presence) response(eventId uuid.UUID, scope dtos.PresenceScope) dtos.PresenceResponse {
+	trend := make([]dtos.PresenceTrendResponse, len(p.trend))
+	copy(trend, p.trend)
+
+	// the peak of every instance together can be higher than the peak of this instance
+	peak, peakAt := p.peak, p.peakAt
+	if p.sumPeak > peak {
+		peak, peakAt = p.sumPeak, p.sumPeakAt
+	}
+
+	return dtos.PresenceResponse{
+		EventID: eventId,
+		Scope:   scope,
+		Current: p.current,
+		Peak:    peak,
+		PeakAt:  dtos.CheckNil(peakAt),
+		Joins:   p.joins,
+		Leaves:  p.leaves,
+		Trend:   trend,
+	}
+}
+
+// public checks if the message is meant for everyone
+func (a Audience) public() bool {
+	return a.AdminID == nil && a.UserID == nil && len(a.Roles) == 0
+}
+
+// includes checks if the session belongs to the audience
+func (a Audience) includes(s *melody.Session) bool {
+	if a.public() {
+		return true
+	}
+
+	if a.AdminID != nil {
+		if value, exists := s.Get("currentAdmin"); exists {
+			if admin, ok := value.(models.Admin); ok && admin.AdminID == *a.AdminID {
+				return true
+			}
+		}
+	}
+
+	if a.UserID != nil {
+		if user, ok := s.Request.Context().Value("user").(dtos.User); ok && user.ID == *a.UserID {
+			return true
+		}
+	}
+
+	if len(a.Roles) > 0 {
+		value, exists := s.Get("role")
+		role, ok := value.(dtos.Role)
+		if !exists || !ok {
+			role = dtos.ParticipantRole
+		}
+
+		for _, audienceRole := range a.Roles {
+			if audienceRole == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
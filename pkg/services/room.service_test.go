@@ -0,0 +1,205 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HudYuSa/mydeen/pkg/dtos"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/olahol/melody"
+)
+
+func TestRoomMissed(t *testing.T) {
+	adminId := uuid.New()
+
+	tests := []struct {
+		name string
+		// the audience of every message sent to the room, nil for everyone
+		sent    []*Audience
+		lastSeq uint64
+		want    []uint64
+		ok      bool
+	}{
+		{"nothing sent", nil, 0, []uint64{}, true},
+		{"nothing missed", make([]*Audience, 3), 3, []uint64{}, true},
+		{"missed some", make([]*Audience, 3), 1, []uint64{2, 3}, true},
+		{"missed everything", make([]*Audience, 3), 0, []uint64{1, 2, 3}, true},
+		{"sequence from the future", make([]*Audience, 3), 4, nil, false},
+		{"private messages are skipped", []*Audience{nil, {AdminID: &adminId}, nil}, 0, []uint64{1, 3}, true},
+		{"missed all that is kept", make([]*Audience, roomLogSize+5), 5, seqRange(6, roomLogSize+5), true},
+		{"gap after the oldest kept", make([]*Audience, roomLogSize+5), 4, nil, false},
+		{"gap from the start", make([]*Audience, roomLogSize+5), 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := NewRoomService(melody.New(), NewMemoryBroadcaster())
+			eventId := uuid.New()
+			otherEventId := uuid.New()
+
+			for i, audience := range tt.sent {
+				msg := dtos.WebSocketRespondJson(dtos.Question, dtos.CreateQuestionType, i)
+				if audience == nil {
+					rs.Broadcast(eventId, msg)
+				} else {
+					rs.BroadcastTo(eventId, msg, *audience)
+				}
+				// another room has its own sequence
+				rs.Broadcast(otherEventId, msg)
+			}
+
+			if seq := rs.Seq(eventId); seq != uint64(len(tt.sent)) {
+				t.Fatalf("seq = %d, want %d", seq, len(tt.sent))
+			}
+
+			missed, ok := rs.Missed(eventId, tt.lastSeq)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				// the client needs a snapshot
+				return
+			}
+
+			if len(missed) != len(tt.want) {
+				t.Fatalf("got %d messages, want %d", len(missed), len(tt.want))
+			}
+			for i, message := range missed {
+				if message.Seq != tt.want[i] {
+					t.Errorf("message %d: seq = %d, want %d", i, message.Seq, tt.want[i])
+				}
+
+				// the message carries its sequence for the client
+				var response struct {
+					Seq  uint64 `json:"seq"`
+					Data int    `json:"data"`
+				}
+				if err := json.Unmarshal(message.Msg, &response); err != nil {
+					t.Fatalf("message %d: %v", i, err)
+				}
+				if response.Seq != message.Seq || uint64(response.Data)+1 != message.Seq {
+					t.Errorf("message %d: seq %d with data %d, want seq %d", i, response.Seq, response.Data, message.Seq)
+				}
+			}
+		})
+	}
+}
+
+// seqRange returns the sequence numbers from first to last
+func seqRange(first uint64, last uint64) []uint64 {
+	seqs := []uint64{}
+	for seq := first; seq <= last; seq++ {
+		seqs = append(seqs, seq)
+	}
+	return seqs
+}
+
+func TestRoomJoinRacingBroadcast(t *testing.T) {
+	const (
+		before = 5
+		during = 50
+	)
+
+	tests := []struct {
+		name    string
+		lastSeq *uint64
+		// the first sequence the client has to get, the ones before it it already has
+		first uint64
+	}{
+		{"new client", nil, 0},
+		{"reconnecting client", func() *uint64 { seq := uint64(2); return &seq }(), 3},
+		{"current client", func() *uint64 { seq := uint64(before); return &seq }(), before + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the race doesn't happen every time, so it's run a few times
+			for run := 0; run < 20; run++ {
+				m := melody.New()
+				rs := NewRoomService(m, NewMemoryBroadcaster())
+				eventId := uuid.New()
+
+				sessions := make(chan *melody.Session, 1)
+				m.HandleConnect(func(s *melody.Session) {
+					sessions <- s
+				})
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					m.HandleRequest(w, r)
+				}))
+
+				conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+				if err != nil {
+					t.Fatalf("dial: %v", err)
+				}
+				s := <-sessions
+
+				for i := 0; i < before; i++ {
+					rs.Broadcast(eventId, dtos.WebSocketRespondJson(dtos.Question, dtos.CreateQuestionType, i))
+				}
+
+				// the room keeps getting messages while the client joins
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					for i := before; i < before+during; i++ {
+						rs.Broadcast(eventId, dtos.WebSocketRespondJson(dtos.Question, dtos.CreateQuestionType, i))
+					}
+				}()
+
+				ok := rs.Join(s, eventId, tt.lastSeq, func(seq uint64) []byte {
+					return dtos.SetWebSocketSeq(dtos.WebSocketRespondJson(dtos.Room, dtos.JoinRoomType, nil), seq)
+				})
+				if !ok {
+					t.Fatal("join asked for a snapshot")
+				}
+				<-done
+
+				checkRoomMessages(t, conn, tt.first, before+during)
+
+				conn.Close()
+				server.Close()
+				m.Close()
+			}
+		})
+	}
+}
+
+// checkRoomMessages reads the join response and then every message of the room up to last
+// a reconnecting client gets every message from first once and in order, a new client the ones after its join sequence
+func checkRoomMessages(t *testing.T, conn *websocket.Conn, first uint64, last uint64) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var response struct {
+		Type dtos.WebSocketType `json:"type"`
+		Seq  uint64             `json:"seq"`
+	}
+
+	if err := conn.ReadJSON(&response); err != nil {
+		t.Fatalf("read join: %v", err)
+	}
+	if response.Type != dtos.JoinRoomType {
+		t.Fatalf("first message is %q, want the join response", response.Type)
+	}
+
+	next := response.Seq + 1
+	if first > 0 {
+		next = first
+	}
+
+	for next <= last {
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("read seq %d: %v", next, err)
+		}
+		if response.Seq != next {
+			t.Fatalf("got seq %d, want %d", response.Seq, next)
+		}
+		next++
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/HudYuSa/mydeen/db/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// the word filter looks for the banned words of an event and of the global list in a question
+// the question is lowercased and the common character substitutions are undone before matching
+// so "H3LL0!" and "he11o" hit the banned word "hello"
+// a word only hits as a whole word, which is checked on the question as it was written
+// so the "!" in "hello!" ends the word even though it's undone to an "i"
+// regex patterns are matched against the same texts and against the question only lowercased
+
+// the characters people put in place of letters to get around a filter
+// a character that stands for more than one letter has its choices in order, the text is matched once with every choice
+var filterSubstitutions = map[rune][]rune{
+	'0': {'o'},
+	'1': {'i', 'l'},
+	'3': {'e'},
+	'4': {'a'},
+	'5': {'s'},
+	'7': {'t'},
+	'8': {'b'},
+	'9': {'g'},
+	'@': {'a'},
+	'$': {'s'},
+	'!': {'i'},
+	'|': {'i', 'l'},
+	'+': {'t'},
+	'€': {'e'},
+}
+
+// the most choices a substituted character has
+const maxFilterChoices = 2
+
+type WordFilter interface {
+	// Check finds the banned words of the event and of the global list in the content
+	Check(db *gorm.DB, eventId uuid.UUID, content string) (WordFilterResult, error)
+	// Log keeps the hit for the admin to review, a hit that can't be kept is only logged
+	Log(ctx context.Context, event *models.Event, userId uuid.UUID, questionId *uuid.UUID, content string, result WordFilterResult)
+}
+
+// WordFilterResult is what the filter found in a content
+type WordFilterResult struct {
+	// the parts of the content that hit a banned word, as they were written
+	Matches []string
+	// the content with every hit replaced by asterisks
+	Masked string
+}
+
+// Hit tells if the content has any banned word
+func (r WordFilterResult) Hit() bool {
+	return len(r.Matches) > 0
+}
+
+type wordFilter struct {
+	DB *gorm.DB
+}
+
+func NewWordFilter(db *gorm.DB) WordFilter {
+	return &wordFilter{
+		DB: db,
+	}
+}
+
+func (wf *wordFilter) Check(db *gorm.DB, eventId uuid.UUID, content string) (WordFilterResult, error) {
+	words := []models.BannedWord{}
+	wordsResult := db.Where("event_id = ? OR event_id IS NULL", eventId).Find(&words)
+	if wordsResult.Error != nil {
+		return WordFilterResult{Masked: content}, wordsResult.Error
+	}
+
+	return MatchBannedWords(words, content), nil
+}
+
+func (wf *wordFilter) Log(ctx context.Context, event *models.Event, userId uuid.UUID, questionId *uuid.UUID, content string, result WordFilterResult) {
+	hit := models.FilterHit{
+		EventID:    event.EventID,
+		UserID:     userId,
+		QuestionID: questionId,
+		Content:    content,
+		Matched:    strings.Join(result.Matches, ", "),
+		Action:     event.FilterAction,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	hitResult := wf.DB.WithContext(ctx).Create(&hit)
+	if hitResult.Error != nil {
+		log.Println("filter hit err: ", hitResult.Error, " event: ", event.EventID, " matched: ", hit.Matched)
+	}
+}
+
+// MatchBannedWords finds the words in the content
+func MatchBannedWords(words []models.BannedWord, content string) WordFilterResult {
+	result := WordFilterResult{Masked: content}
+	if len(words) == 0 {
+		return result
+	}
+
+	original := []rune(content)
+	texts := []string{strings.Map(unicode.ToLower, content)}
+	for choice := 0; choice < maxFilterChoices; choice++ {
+		texts = append(texts, normalizeFilterText(content, choice))
+	}
+
+	// the runes of the content that hit a banned word
+	hits := make([]bool, len(original))
+	// the hits already found, every text can find the same one
+	found := map[[2]int]bool{}
+
+	for _, word := range words {
+		pattern, err := CompileBannedWord(word.Pattern, word.Regex)
+		if err != nil {
+			// the patterns are checked when they're added, this is a pattern that broke since
+			log.Println("banned word err: ", err, " word: ", word.WordID)
+			continue
+		}
+
+		for _, text := range texts {
+			for _, match := range pattern.FindAllStringIndex(text, -1) {
+				start, end := match[0], match[1]
+				if start == end {
+					continue
+				}
+
+				// every text has one rune for every rune of the content
+				from := utf8.RuneCountInString(text[:start])
+				to := from + utf8.RuneCountInString(text[start:end])
+
+				// a word only hits when it isn't a part of a longer word
+				if !word.Regex && !isWordBoundary(original, from, to) {
+					continue
+				}
+
+				if found[[2]int{from, to}] {
+					continue
+				}
+				found[[2]int{from, to}] = true
+
+				result.Matches = append(result.Matches, string(original[from:to]))
+				for i := from; i < to; i++ {
+					hits[i] = true
+				}
+			}
+		}
+	}
+
+	if !result.Hit() {
+		return result
+	}
+
+	masked := make([]rune, len(original))
+	for i, r := range original {
+		if hits[i] && !unicode.IsSpace(r) {
+			masked[i] = '*'
+		} else {
+			masked[i] = r
+		}
+	}
+	result.Masked = string(masked)
+
+	return result
+}
+
+// CompileBannedWord compiles the pattern of a banned word
+// a word is matched with any whitespace between its parts, a regex is matched as it is
+func CompileBannedWord(pattern string, regex bool) (*regexp.Regexp, error) {
+	if regex {
+		return regexp.Compile("(?i)" + pattern)
+	}
+
+	parts := strings.Fields(NormalizeFilterText(pattern))
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	return regexp.Compile(strings.Join(parts, `\s+`))
+}
+
+// isWordBoundary tells if the runes have no letter or number right before from and right at to
+// the runes are the content as it was written, a substituted character around a word isn't a letter there
+func isWordBoundary(runes []rune, from int, to int) bool {
+	if from > 0 && (unicode.IsLetter(runes[from-1]) || unicode.IsNumber(runes[from-1])) {
+		return false
+	}
+	if to < len(runes) && (unicode.IsLetter(runes[to]) || unicode.IsNumber(runes[to])) {
+		return false
+	}
+	return true
+}
+
+// NormalizeFilterText lowercases the text and undoes the character substitutions with their first choice
+// every rune is replaced by exactly one rune, so a match in the normalized text is in the same place in the text
+func NormalizeFilterText(text string) string {
+	return normalizeFilterText(text, 0)
+}
+
+// normalizeFilterText is NormalizeFilterText with the given choice for the characters that have more than one
+func normalizeFilterText(text string, choice int) string {
+	var b strings.Builder
+	b.Grow(len(text))
+
+	for _, r := range text {
+		if substitutes, ok := filterSubstitutions[r]; ok {
+			r = substitutes[0]
+			if choice < len(substitutes) {
+				r = substitutes[choice]
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/HudYuSa/mydeen/db/models"
+)
+
+func TestMatchBannedWords(t *testing.T) {
+	hello := []models.BannedWord{{Pattern: "hello"}}
+
+	tests := []struct {
+		name    string
+		words   []models.BannedWord
+		content string
+		matches []string
+		masked  string
+	}{
+		{"no words", nil, "hello there", nil, "hello there"},
+		{"plain word", hello, "hello there", []string{"hello"}, "***** there"},
+		{"uppercase", hello, "HELLO there", []string{"HELLO"}, "***** there"},
+		{"substitutions", hello, "H3LL0", []string{"H3LL0"}, "*****"},
+		{"substitutions before an exclamation", hello, "H3LL0!", []string{"H3LL0"}, "*****!"},
+		{"substitutions before exclamations", hello, "say h3ll0!!", []string{"h3ll0"}, "say *****!!"},
+		{"one as l", hello, "he11o", []string{"he11o"}, "*****"},
+		{"pipe as l", hello, "he||o", []string{"he||o"}, "*****"},
+		{"part of a longer word", hello, "othello", nil, "othello"},
+		{"followed by a number", hello, "hello2", nil, "hello2"},
+		{"twice", hello, "hello, hello", []string{"hello", "hello"}, "*****, *****"},
+		{"words with any whitespace", []models.BannedWord{{Pattern: "bad  word"}}, "a bad\tword here", []string{"bad\tword"}, "a ***\t**** here"},
+		{"regex", []models.BannedWord{{Pattern: `sp[a4]m+`, Regex: true}}, "SPAMMM", []string{"SPAMMM"}, "******"},
+		{"regex inside a word", []models.BannedWord{{Pattern: "spam", Regex: true}}, "antispam", []string{"spam"}, "anti****"},
+		{"broken regex is skipped", []models.BannedWord{{Pattern: "(", Regex: true}, {Pattern: "hello"}}, "hello", []string{"hello"}, "*****"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MatchBannedWords(tt.words, tt.content)
+			if !reflect.DeepEqual(result.Matches, tt.matches) {
+				t.Errorf("matches = %q, want %q", result.Matches, tt.matches)
+			}
+			if result.Masked != tt.masked {
+				t.Errorf("masked = %q, want %q", result.Masked, tt.masked)
+			}
+			if result.Hit() != (len(tt.matches) > 0) {
+				t.Errorf("hit = %v, want %v", result.Hit(), len(tt.matches) > 0)
+			}
+		})
+	}
+}
+
+func TestCompileBannedWord(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		regex   bool
+		want    string
+		wantErr bool
+	}{
+		{"word is normalized", "H3llo", false, "hello", false},
+		{"parts are joined by whitespace", " bad   word ", false, `bad\s+word`, false},
+		{"word is quoted", "a.b", false, `a\.b`, false},
+		{"regex is case insensitive", "sp[a4]m", true, "(?i)sp[a4]m", false},
+		{"broken regex", "(", true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := CompileBannedWord(tt.pattern, tt.regex)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q", tt.pattern)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pattern.String() != tt.want {
+				t.Errorf("pattern = %q, want %q", pattern.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// words that don't say anything on their own in a word cloud
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "i": true, "in": true, "is": true, "it": true, "its": true,
+	"my": true, "of": true, "on": true, "or": true, "our": true, "so": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "we": true, "with": true, "you": true, "your": true,
+}
+
+// NormalizeTerm turns a short answer into the term it's counted as
+// it lowercases the answer, drops the punctuation around the words and the stop words
+// and joins the words with single spaces, so "  The Cloud! " and "cloud" are the same term
+// it returns an empty string when no word is left
+func NormalizeTerm(s string) string {
+	words := []string{}
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		word = strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		if word == "" || stopWords[word] {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,31 @@
+package utils
+
+import "testing"
+
+func TestNormalizeTerm(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"empty", "", ""},
+		{"one word", "cloud", "cloud"},
+		{"case and spaces", "  The Cloud! ", "cloud"},
+		{"words joined by single spaces", "Open\tSource \n Software", "open source software"},
+		{"punctuation around the words", "\"fast\", (cheap)...", "fast cheap"},
+		{"punctuation inside a word is kept", "don't e-mail", "don't e-mail"},
+		{"numbers are kept", "Go 1.20", "go 1.20"},
+		{"only stop words", "the and of", ""},
+		{"only punctuation", "!?...", ""},
+		{"stop words after trimming", "(the) it's", "it's"},
+		{"unicode letters", "Über Café", "über café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeTerm(tt.s); got != tt.want {
+				t.Errorf("NormalizeTerm(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}